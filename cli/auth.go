@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	Openapi "jcli/client"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const default_auth_scheme = "Bearer"
+
+// The credentials returned by credential helpers, by helper and engine host.
+// Helpers are run once per process, since they may prompt the user or be
+// rate limited.
+var (
+	helper_credentials      = map[[2]string]*Credentials{}
+	helper_credentials_lock sync.Mutex
+)
+
+// Credentials are sent to the engine in the Authorization header, e.g. when
+// the engine is placed behind an authenticating reverse proxy.
+type Credentials struct {
+	Scheme string `json:"scheme"`
+	Token  string `json:"token"`
+}
+
+func (c *Credentials) Authorization() string {
+	return c.Scheme + " " + c.Token
+}
+
+// Credentials returns the credentials of the context, either the token of the
+// context or the output of its credential helper. It returns nil if no
+// credentials are configured.
+//
+// The credential helper is invoked as '<helper> get' with the engine host on
+// stdin, and must print a JSON object like {"scheme": "Bearer", "token": "..."}
+// on stdout. The scheme is optional. Its output is reused for the lifetime
+// of jcli.
+func (c *EngineContext) Credentials() (*Credentials, error) {
+	credentials := &Credentials{Scheme: c.AuthScheme, Token: c.Token}
+	if credentials.Token == "" && c.CredentialHelper != "" {
+		cached, err := CachedCredentialHelper(c.CredentialHelper, c.Host)
+		if err != nil {
+			return nil, err
+		}
+		credentials = &Credentials{Scheme: cached.Scheme, Token: cached.Token}
+	}
+
+	if credentials.Token == "" {
+		return nil, nil
+	}
+	if credentials.Scheme == "" {
+		credentials.Scheme = default_auth_scheme
+	}
+	return credentials, nil
+}
+
+// CachedCredentialHelper runs the credential helper for the engine host,
+// unless it has already returned credentials for it. Failures are not cached.
+func CachedCredentialHelper(helper string, engine_host string) (*Credentials, error) {
+	helper_credentials_lock.Lock()
+	defer helper_credentials_lock.Unlock()
+
+	key := [2]string{helper, engine_host}
+	if credentials, cached := helper_credentials[key]; cached {
+		return credentials, nil
+	}
+	credentials, err := RunCredentialHelper(helper, engine_host)
+	if err != nil {
+		return nil, err
+	}
+	helper_credentials[key] = credentials
+	return credentials, nil
+}
+
+func RunCredentialHelper(helper string, engine_host string) (*Credentials, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command(helper, "get")
+	cmd.Stdin = strings.NewReader(engine_host)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %s failed: %w", helper, err)
+	}
+
+	credentials := &Credentials{}
+	if err := json.Unmarshal(stdout.Bytes(), credentials); err != nil {
+		return nil, fmt.Errorf("could not parse output of credential helper %s: %w", helper, err)
+	}
+	if credentials.Token == "" {
+		return nil, errors.New("credential helper " + helper + " did not return a token")
+	}
+	return credentials, nil
+}
+
+// AuthRequestEditor adds the Authorization header to every REST call.
+func AuthRequestEditor(credentials *Credentials) Openapi.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", credentials.Authorization())
+		return nil
+	}
+}
+
+// AuthHeaders returns the headers used for the websocket handshake.
+func AuthHeaders(credentials *Credentials) http.Header {
+	headers := http.Header{}
+	if credentials != nil {
+		headers.Set("Authorization", credentials.Authorization())
+	}
+	return headers
+}
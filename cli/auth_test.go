@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+const test_credential_helper = `#!/bin/sh
+read host
+echo "{\"scheme\": \"ApiKey\", \"token\": \"helper-token-for-$host\"}"
+`
+
+const test_counting_credential_helper = `#!/bin/sh
+echo run >> %s
+echo '{"token": "counted"}'
+`
+
+func TestAuthentication(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+	ClearHost(t)
+	var authorization string
+	handler := NetworkListAndAttachHandler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != authorization {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	engine_host := strings.Replace(server.URL, "http://", "tcp://", 1)
+
+	helper := filepath.Join(t.TempDir(), "jcli-credential-helper")
+	assert.NilError(t, os.WriteFile(helper, []byte(test_credential_helper), 0755))
+	assert.NilError(t, ContextCreate(EngineContext{Name: "token", Host: engine_host, Token: "context-token"}))
+	assert.NilError(t, ContextCreate(EngineContext{Name: "helper", Host: engine_host, CredentialHelper: helper}))
+
+	t.Run("requests without credentials are rejected", WithHost(engine_host, func(t *testing.T) {
+		authorization = "Bearer context-token"
+		_, err := NetworkList()
+		var engine_error *EngineError
+		assert.Assert(t, errors.As(err, &engine_error))
+		assert.Equal(t, engine_error.StatusCode, http.StatusUnauthorized)
+	}))
+
+	t.Run("the token in the configuration is used", WithHost(engine_host, func(t *testing.T) {
+		authorization = "Bearer config-token"
+		settings.Set("token", "config-token")
+		defer settings.Set("token", "")
+		ExpectAuthenticatedRESTAndWebsocket(t)
+	}))
+
+	t.Run("the token of the context is used", WithContextName("token", func(t *testing.T) {
+		authorization = "Bearer context-token"
+		ExpectAuthenticatedRESTAndWebsocket(t)
+	}))
+
+	t.Run("the credential helper of the context is used", WithContextName("helper", func(t *testing.T) {
+		authorization = "ApiKey helper-token-for-" + engine_host
+		ExpectAuthenticatedRESTAndWebsocket(t)
+	}))
+
+	t.Run("the credential helper is run once", func(t *testing.T) {
+		runs := filepath.Join(t.TempDir(), "runs")
+		counting_helper := filepath.Join(t.TempDir(), "jcli-counting-credential-helper")
+		assert.NilError(t, os.WriteFile(counting_helper, []byte(fmt.Sprintf(test_counting_credential_helper, runs)), 0755))
+		engine_context := &EngineContext{Host: engine_host, CredentialHelper: counting_helper}
+		for i := 0; i < 3; i++ {
+			credentials, err := engine_context.Credentials()
+			assert.NilError(t, err)
+			assert.Equal(t, credentials.Authorization(), "Bearer counted")
+		}
+		output, err := os.ReadFile(runs)
+		assert.NilError(t, err)
+		assert.Equal(t, string(output), "run\n")
+	})
+
+	t.Run("a failing credential helper is reported", func(t *testing.T) {
+		_, err := (&EngineContext{CredentialHelper: "/bin/false"}).Credentials()
+		assert.ErrorContains(t, err, "credential helper /bin/false failed")
+	})
+}
+
+func ExpectAuthenticatedRESTAndWebsocket(t *testing.T) {
+	response, err := NetworkList()
+	assert.NilError(t, err)
+	assert.Equal(t, *(*response.JSON200)[0].Name, "default")
+
+	_, _, ws, err := Dial("/containers/abc/attach", nil)
+	assert.NilError(t, err)
+	defer ws.Close()
+	_, message, err := ws.ReadMessage()
+	assert.NilError(t, err)
+	assert.Equal(t, string(message), "ok:")
+}
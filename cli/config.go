@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const config_env_prefix = "JCLI"
+
+// Command specific flags with this annotation can also be set using their name
+// as key in the configuration, like global flags, e.g. 'detach-keys'.
+const config_global_key_annotation = "jcli_config_global_key"
+
+var (
+	config_file string
+	settings    = viper.New()
+)
+
+// ConfigDir returns the directory where jcli keeps its configuration,
+// $XDG_CONFIG_HOME/jcli or ~/.config/jcli if XDG_CONFIG_HOME is not set.
+func ConfigDir() string {
+	config_home := os.Getenv("XDG_CONFIG_HOME")
+	if config_home == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		config_home = filepath.Join(home, ".config")
+	}
+	return filepath.Join(config_home, "jcli")
+}
+
+// LoadConfig reads the configuration file and enables the JCLI_* environment
+// variables. The file given with --config (or JCLI_CONFIG) must exist, whereas
+// a missing default configuration file is ignored.
+func LoadConfig() error {
+	settings = viper.New()
+	settings.SetEnvPrefix(config_env_prefix)
+	settings.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	settings.AutomaticEnv()
+
+	path := config_file
+	if path == "" {
+		path = os.Getenv(config_env_prefix + "_CONFIG")
+	}
+	if path == "" {
+		path = filepath.Join(ConfigDir(), "config.yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	settings.SetConfigFile(path)
+	if err := settings.ReadInConfig(); err != nil {
+		return fmt.Errorf("could not read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyConfig sets the flags of cmd that have not been given on the command
+// line, using the environment or the configuration file. Global flags use
+// their name as key (e.g. 'host' and JCLI_HOST) while command specific flags
+// are prefixed with the command path (e.g. 'container.create.jailparam' and
+// JCLI_CONTAINER_CREATE_JAILPARAM). Global flags can also be given per
+// command, e.g. 'container.ls.format' takes precedence over 'format'.
+func ApplyConfig(cmd *cobra.Command) error {
+	var err error
+	apply := func(prefixes ...string) func(*pflag.Flag) {
+		return func(flag *pflag.Flag) {
+			if err != nil || flag.Changed || flag.Name == "config" {
+				return
+			}
+			for _, prefix := range prefixes {
+				key := flag.Name
+				if prefix != "" {
+					key = prefix + "." + flag.Name
+				}
+				if !settings.IsSet(key) {
+					continue
+				}
+				if set_err := setFlagFromConfig(flag, settings.Get(key)); set_err != nil {
+					err = fmt.Errorf("invalid value for '%s' in the configuration: %w", key, set_err)
+				}
+				return
+			}
+		}
+	}
+
+	cmd.InheritedFlags().VisitAll(apply(ConfigKey(cmd), ""))
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if _, global_key := flag.Annotations[config_global_key_annotation]; global_key {
+			apply(ConfigKey(cmd), "")(flag)
+		} else {
+			apply(ConfigKey(cmd))(flag)
+		}
+	})
+	return err
+}
+
+// ConfigKey returns the configuration prefix of a command, e.g. 'container.create'.
+func ConfigKey(cmd *cobra.Command) string {
+	return strings.Join(strings.Fields(cmd.CommandPath())[1:], ".")
+}
+
+func setFlagFromConfig(flag *pflag.Flag, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		slice_value, is_slice := flag.Value.(pflag.SliceValue)
+		if !is_slice {
+			return fmt.Errorf("expected a single value, got a list")
+		}
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return slice_value.Replace(items)
+	}
+	return flag.Value.Set(fmt.Sprint(value))
+}
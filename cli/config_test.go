@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gotest.tools/v3/assert"
+)
+
+const test_config = `
+host: unix:///var/run/jocker.sock
+container:
+  create:
+    jailparam:
+      - mount.devfs
+      - allow.raw_sockets
+`
+
+func TestConfigPrecedence(t *testing.T) {
+	config_home := t.TempDir()
+	assert.NilError(t, os.MkdirAll(filepath.Join(config_home, "jcli"), 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(config_home, "jcli", "config.yaml"), []byte(test_config), 0644))
+	SetEnvForTest(t, "XDG_CONFIG_HOME", config_home)
+	SetEnvForTest(t, "JCLI_HOST", "")
+
+	t.Run("values from the config file are used when no flags are given", func(t *testing.T) {
+		host, jailparams := ExecuteConfigTestCommand(t)
+		assert.Equal(t, host, "unix:///var/run/jocker.sock")
+		assert.DeepEqual(t, jailparams, []string{"mount.devfs", "allow.raw_sockets"})
+	})
+
+	t.Run("environment variables take precedence over the config file", func(t *testing.T) {
+		SetEnvForTest(t, "JCLI_HOST", "tcp://envhost:8085")
+		SetEnvForTest(t, "JCLI_CONTAINER_CREATE_JAILPARAM", "allow.mount")
+		host, jailparams := ExecuteConfigTestCommand(t)
+		assert.Equal(t, host, "tcp://envhost:8085")
+		assert.DeepEqual(t, jailparams, []string{"allow.mount"})
+	})
+
+	t.Run("flags take precedence over environment variables", func(t *testing.T) {
+		SetEnvForTest(t, "JCLI_HOST", "tcp://envhost:8085")
+		host, jailparams := ExecuteConfigTestCommand(t, "--host", "tcp://flaghost:8085", "--jailparam", "allow.chflags")
+		assert.Equal(t, host, "tcp://flaghost:8085")
+		assert.DeepEqual(t, jailparams, []string{"allow.chflags"})
+	})
+
+	t.Run("an explicitly given config file must exist", func(t *testing.T) {
+		old_config_file := config_file
+		config_file = filepath.Join(config_home, "missing.yaml")
+		defer func() { config_file = old_config_file }()
+		assert.ErrorContains(t, LoadConfig(), "could not read config file")
+	})
+}
+
+// ExecuteConfigTestCommand runs a 'jcli container create' look-alike with the
+// given arguments and returns the resulting host and jail parameters.
+func ExecuteConfigTestCommand(t *testing.T, args ...string) (string, []string) {
+	var test_host string
+	var jailparams []string
+
+	root := &cobra.Command{Use: "jcli", PersistentPreRunE: RootCmd.PersistentPreRunE}
+	root.PersistentFlags().StringVarP(&test_host, "host", "H", "", "")
+	container := &cobra.Command{Use: "container"}
+	create := &cobra.Command{Use: "create", Run: func(cmd *cobra.Command, args []string) {}}
+	create.Flags().StringSliceVarP(&jailparams, "jailparam", "J", []string{"mount.devfs"}, "")
+	container.AddCommand(create)
+	root.AddCommand(container)
+
+	root.SetArgs(append([]string{"container", "create"}, args...))
+	assert.NilError(t, root.Execute())
+	return test_host, jailparams
+}
+
+func SetEnvForTest(t *testing.T, key, value string) {
+	old_value, was_set := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if was_set {
+			os.Setenv(key, old_value)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
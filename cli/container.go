@@ -4,16 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	Openapi "jcli/client"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-const jocker_engine_url = "http://localhost:8085/"
-const ws_container_attach = "ws://localhost:8085/containers/%s/attach"
-const succesful_ws_exit = "websocket: close 1000 (normal): exit:"
+const ws_container_attach = "/containers/%s/attach"
+
+// How long 'rm --force' waits for a stopped container to no longer be running.
+const stop_wait_timeout = 10 * time.Second
+const stop_poll_interval = 100 * time.Millisecond
 
 func ContainerCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -21,27 +29,23 @@ func ContainerCommand() *cobra.Command {
 		Short:                 "Manage containers",
 		Long:                  `Manage containers`,
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("The container main command have been executed")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
 		},
 	}
 
 	cmd.AddCommand(ContainerCreateCommand())
+	cmd.AddCommand(ContainerRunCommand())
 	cmd.AddCommand(ContainerRemoveCommand())
 	cmd.AddCommand(ContainerStartCommand())
+	cmd.AddCommand(ContainerAttachCommand())
 	cmd.AddCommand(ContainerStopCommand())
 	cmd.AddCommand(ContainerListCommand())
 	return cmd
 }
 
 func ContainerCreateCommand() *cobra.Command {
-	config := Openapi.ContainerCreateJSONRequestBody{
-		Networks:  &([]string{}),
-		Volumes:   &([]string{}),
-		Env:       &([]string{}),
-		JailParam: &([]string{}),
-	}
-
+	config := NewContainerConfig()
 	var name string
 
 	cmd := &cobra.Command{
@@ -50,256 +54,701 @@ func ContainerCreateCommand() *cobra.Command {
 		Long:                  `Create a new container`,
 		Args:                  cobra.MinimumNArgs(1),
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			response, err := PostContainerCreate(&name, config, args)
-			if err == nil {
-				fmt.Println(response.JSON201.Id)
+			if err != nil {
+				return err
 			}
+			return PrintIdResponse(response.JSON201)
 		},
 	}
 
-	flags := cmd.Flags()
-	flags.StringVar(&name, "name", "", "Assign a name to the container")
+	add_container_create_flags(cmd.Flags(), &name, config)
+	return cmd
+}
+
+func NewContainerConfig() Openapi.ContainerCreateJSONRequestBody {
+	return Openapi.ContainerCreateJSONRequestBody{
+		Networks:  &([]string{}),
+		Volumes:   &([]string{}),
+		Env:       &([]string{}),
+		JailParam: &([]string{}),
+	}
+}
+
+// add_container_create_flags adds the flags shared by 'container create' and 'container run'.
+func add_container_create_flags(flags *pflag.FlagSet, name *string, config Openapi.ContainerCreateJSONRequestBody) {
+	flags.StringVar(name, "name", "", "Assign a name to the container")
 	flags.StringSliceVar(config.Networks, "network", []string{}, "Connect a container to a network")
 	flags.StringSliceVarP(config.Volumes, "volume", "v", []string{}, "Bind mount a volume to the container")
 	flags.StringSliceVarP(config.Env, "env", "e", []string{}, "Set environment variables (e.g. --env FIRST=env --env SECOND=env)")
 	flags.StringSliceVarP(config.JailParam, "jailparam", "J", []string{"mount.devfs"}, "Specify a jail parameter, see jail(8) for details")
+}
+
+func ContainerRunCommand() *cobra.Command {
+	config := NewContainerConfig()
+	var name string
+	var detach, remove bool
+	var options AttachOptions
+
+	cmd := &cobra.Command{
+		Use:                   "run [OPTIONS] IMAGE [COMMAND] [ARG...]",
+		Short:                 "Create and start a new container",
+		Long:                  "Create and start a new container. Attach to STDOUT/STDERR unless --detach is given",
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if detach && remove {
+				return errors.New("--rm cannot be used with --detach, since the engine does not remove containers when they exit")
+			}
+			if detach && (options.Interactive || options.TTY) {
+				return errors.New("--interactive and --tty cannot be used with --detach")
+			}
+			if err := options.Validate(); err != nil {
+				return err
+			}
+			return ContainerRun(&name, config, args, detach, remove, options)
+		},
+	}
+
+	flags := cmd.Flags()
+	// Flags after the image belong to the command of the container.
+	flags.SetInterspersed(false)
+	add_container_create_flags(flags, &name, config)
+	flags.BoolVarP(&detach, "detach", "d", false, "Start the container in the background and print its ID")
+	flags.BoolVar(&remove, "rm", false, "Remove the container when it exits, or when jcli is interrupted")
+	add_attach_flags(flags, &options)
 	return cmd
 }
 
+// ContainerRun creates and starts a container. Unless detach is true, the
+// output of the container is printed until it exits. If remove is true, the
+// container is removed afterwards, even if jcli is interrupted.
+func ContainerRun(name *string, config Openapi.ContainerCreateJSONRequestBody, args []string, detach bool, remove bool, options AttachOptions) error {
+	response, err := PostContainerCreate(name, config, args)
+	if err != nil {
+		return err
+	}
+	container_id := response.JSON201.Id
+
+	if detach {
+		client, err := NewHTTPClient()
+		if err != nil {
+			return err
+		}
+		if _, err = StartSingleContainer(client, container_id); err != nil {
+			return err
+		}
+		return PrintIdResponse(response.JSON201)
+	}
+
+	_, err = StartAttached(container_id, options)
+	if errors.Is(err, ErrDetached) {
+		print_detached(container_id)
+		if remove {
+			fmt.Fprintf(os.Stderr, "The container is not removed, remove it with 'jcli container rm -f %s'\n", container_id)
+		}
+		return nil
+	}
+	if remove {
+		// The container is removed even if jcli has been interrupted. A
+		// second signal still terminates jcli immediately.
+		client, remove_err := NewHTTPClient()
+		if remove_err == nil {
+			_, remove_err = ForceRemoveContainer(context.Background(), client, container_id)
+		}
+		if remove_err != nil {
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", remove_err)
+				return err
+			}
+			return remove_err
+		}
+	}
+	return err
+}
+
 func PostContainerCreate(name *string, body Openapi.ContainerCreateJSONRequestBody, args []string) (*Openapi.ContainerCreateResponse, error) {
 	container_cmd := args[1:]
 	image := args[0]
 	body.Cmd = &container_cmd
 	body.Image = &image
 
+	if body.Networks == nil || len(*body.Networks) == 0 {
+		engine_context, err := ActiveContext()
+		if err != nil {
+			return nil, fmt.Errorf("could not determine engine context: %w", err)
+		}
+		if len(engine_context.Networks) > 0 {
+			body.Networks = &engine_context.Networks
+		}
+	}
+
 	params := Openapi.ContainerCreateParams{}
 	if *name != "" {
 		params = Openapi.ContainerCreateParams{Name: name}
 	}
 
-	client := NewHTTPClient()
-
-	response, err := client.ContainerCreateWithResponse(context.TODO(), &params, body)
+	client, err := NewHTTPClient()
 	if err != nil {
-		fmt.Println("Could not connect to jocker engine daemon: ", err)
-		return response, err
+		return nil, err
 	}
+	ctx, cancel := RequestContext()
+	defer cancel()
 
-	if response.StatusCode() != 201 {
-		fmt.Println("Jocker engine returned unsuccesful statuscode: ", response.Status())
-		return response, errors.New("non-200 statuscode")
-	}
-	return response, nil
+	response, err := client.ContainerCreateWithResponse(ctx, &params, body)
+	err = verify_response("container create", response, 201, err)
+	return response, err
 }
 
 func ContainerRemoveCommand() *cobra.Command {
+	var parallel int
+	var force bool
+
 	cmd := &cobra.Command{
-		Use:                   "rm",
+		Use:                   "rm [OPTIONS] CONTAINER [CONTAINER...]",
 		Short:                 "Remove one or more containers",
 		Long:                  `Remove one or more containers`,
 		Args:                  cobra.MinimumNArgs(1),
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			response, err := PostContainerRemove(args)
-			if err == nil {
-				fmt.Println(response.JSON200.Id)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate_parallel(parallel); err != nil {
+				return err
 			}
+			client, err := NewHTTPClient()
+			if err != nil {
+				return err
+			}
+			responses, errs := RemoveContainers(client, args, parallel, force)
+			return print_results(errs, func(idx int) Openapi.IdResponse { return *responses[idx].JSON200 })
 		},
 	}
+	cmd.Flags().IntVar(&parallel, "parallel", default_parallel, "Maximum number of containers to remove concurrently")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Stop running containers before removing them")
 	return cmd
 }
 
-func PostContainerRemove(args []string) (*Openapi.ContainerDeleteResponse, error) {
-	container_id := args[0]
-	client := NewHTTPClient()
-	response, _ := client.ContainerDeleteWithResponse(context.TODO(), container_id)
-	status_code := response.StatusCode()
+// RemoveContainers removes every container, with at most parallel requests
+// to the engine at a time. The responses and errors are in the order of name_or_ids.
+func RemoveContainers(client *Openapi.ClientWithResponses, name_or_ids []string, parallel int, force bool) ([]*Openapi.ContainerDeleteResponse, []error) {
+	errs := make([]error, len(name_or_ids))
+	responses := make([]*Openapi.ContainerDeleteResponse, len(name_or_ids))
+	run_parallel(len(name_or_ids), parallel, func(idx int) {
+		if force {
+			responses[idx], errs[idx] = ForceRemoveContainer(root_ctx, client, name_or_ids[idx])
+		} else {
+			responses[idx], errs[idx] = PostContainerRemove(client, name_or_ids[idx])
+		}
+	})
+	return responses, errs
+}
 
+// ForceRemoveContainer stops the container and waits for it to be stopped,
+// before removing it. A container that is already stopped is just removed.
+// The requests are cancelled when ctx is done.
+func ForceRemoveContainer(ctx context.Context, client *Openapi.ClientWithResponses, container string) (*Openapi.ContainerDeleteResponse, error) {
+	stop_response, err := stop_container(ctx, client, container)
+	var engine_error *EngineError
 	switch {
-	case status_code == 200:
-		//fmt.Println("succesfully removed container")
-		return response, nil
-	case status_code == 404:
-		return response, errors.New("no such container")
-	case status_code == 500:
-		return response, errors.New("internal server error")
+	case err == nil:
+		if err = WaitUntilStopped(ctx, client, stop_response.JSON200.Id); err != nil {
+			return nil, err
+		}
+	case errors.As(err, &engine_error) && engine_error.StatusCode == http.StatusNotModified:
+		// The container is already stopped, or it exited in the meantime.
+	case errors.As(err, &engine_error) && engine_error.StatusCode == http.StatusNotFound:
+		// The container does not exist, or it was removed in the meantime,
+		// which is reported by the engine when removing it.
 	default:
-		return response, errors.New("unknown status-code received from jocker engine: " + response.Status())
+		return nil, err
+	}
+	return remove_container(ctx, client, container)
+}
+
+// WaitUntilStopped waits until the container is no longer listed as running,
+// or ctx is done.
+func WaitUntilStopped(ctx context.Context, client *Openapi.ClientWithResponses, container_id string) error {
+	deadline := time.Now().Add(stop_wait_timeout)
+	running_only := false
+	for {
+		request_ctx, cancel := RequestContextFrom(ctx)
+		response, err := client.ContainerListWithResponse(request_ctx, &Openapi.ContainerListParams{All: &running_only})
+		cancel()
+		if err = verify_response("container list", response, 200, err); err != nil {
+			return err
+		}
+		running := false
+		for _, container := range *response.JSON200 {
+			running = running || *container.Id == container_id
+		}
+		if !running {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("container %s did not stop within %s", container_id, stop_wait_timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return request_error(ctx.Err())
+		case <-time.After(stop_poll_interval):
+		}
 	}
 }
 
+func PostContainerRemove(client *Openapi.ClientWithResponses, container string) (*Openapi.ContainerDeleteResponse, error) {
+	return remove_container(root_ctx, client, container)
+}
+
+func remove_container(parent context.Context, client *Openapi.ClientWithResponses, container string) (*Openapi.ContainerDeleteResponse, error) {
+	ctx, cancel := RequestContextFrom(parent)
+	defer cancel()
+
+	response, err := client.ContainerDeleteWithResponse(ctx, container)
+	err = verify_response("container remove", response, 200, err)
+	return response, err
+}
+
 func ContainerStartCommand() *cobra.Command {
 	var attach bool
+	var options AttachOptions
 	cmd := &cobra.Command{
 		Use:                   "start [OPTIONS] CONTAINER [CONTAINER...]",
 		Short:                 "Start one or more stopped containers",
 		Long:                  "Start one or more stopped containers. Attach to STDOUT/STDERR if only one container is started",
 		Args:                  cobra.MinimumNArgs(1),
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if attach {
-				StartAndAttachToContainer(args)
-			} else {
-				StartSeveralContainers(args)
+				if err := options.Validate(); err != nil {
+					return err
+				}
+				return StartAndAttachToContainer(args, options)
 			}
+			_, err := StartSeveralContainers(args)
+			return err
 		},
 	}
 
 	cmd.Flags().BoolVarP(&attach, "attach", "a", true, "Attach STDOUT/STDERR")
+	add_attach_flags(cmd.Flags(), &options)
 	return cmd
 }
 
-func StartSeveralContainers(args []string) []string {
-	client := NewHTTPClient()
+// AttachOptions are the options of commands attaching to a container.
+type AttachOptions struct {
+	// Stdin is forwarded to the container if Interactive is true.
+	Interactive bool
+	// TTY puts the terminal in raw mode while attached, so that every key is sent to the container.
+	TTY bool
+	// DetachKeys is the key sequence for detaching from the container when
+	// Interactive and TTY are true, see ParseDetachKeys.
+	DetachKeys string
+	// SigProxy stops the container when jcli receives one of proxied_signals.
+	SigProxy bool
+}
+
+// The signals that stop an attached container with --sig-proxy. The attach
+// protocol has no frame for sending a signal to the container.
+var proxied_signals = []os.Signal{syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+func add_attach_flags(flags *pflag.FlagSet, options *AttachOptions) {
+	flags.BoolVarP(&options.Interactive, "interactive", "i", false, "Forward STDIN to the container when attached")
+	flags.BoolVarP(&options.TTY, "tty", "t", false, "Put the terminal in raw mode when attached")
+	flags.StringVar(&options.DetachKeys, "detach-keys", default_detach_keys, "Key sequence for detaching from the container")
+	_ = flags.SetAnnotation("detach-keys", config_global_key_annotation, []string{"true"})
+	flags.BoolVar(&options.SigProxy, "sig-proxy", true, "Stop the container when jcli receives SIGTERM, SIGHUP or SIGQUIT while attached")
+}
+
+// Validate returns an error if the options cannot be used, before the container is created or started.
+func (o AttachOptions) Validate() error {
+	if o.TTY {
+		if err := ValidateTerminal(os.Stdin); err != nil {
+			return err
+		}
+	}
+	_, err := ParseDetachKeys(o.DetachKeys)
+	return err
+}
+
+func print_detached(container string) {
+	fmt.Fprintf(os.Stderr, "Detached from container %s, which keeps running. Reattach with 'jcli container attach %s'\n", container, container)
+}
+
+// StartSeveralContainers starts every container and prints the IDs of the
+// started containers, followed by the failures, if any.
+func StartSeveralContainers(args []string) ([]string, error) {
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
 	container_ids := make([]string, len(args))
-	var container_id string
+	errs := make([]error, len(args))
 	for i, container := range args {
-		container_id = StartSingleContainer(client, container)
-		container_ids[i] = container_id
-		if container_id != "" {
-			fmt.Println(container_id)
-		}
+		container_ids[i], errs[i] = StartSingleContainer(client, container)
 	}
-	return container_ids
+	return container_ids, print_results(errs, func(idx int) Openapi.IdResponse { return Openapi.IdResponse{Id: container_ids[idx]} })
 }
 
-func StartAndAttachToContainer(args []string) {
+// StartAndAttachToContainer starts a container and prints its output until it
+// exits.
+func StartAndAttachToContainer(args []string, options AttachOptions) error {
 	if len(args) != 1 {
-		fmt.Println("When attaching to STDOUT/STDERR only 1 container can be started")
-		return
+		return errors.New("when attaching to STDOUT/STDERR only 1 container can be started")
+	}
+	_, err := StartAttached(args[0], options)
+	if errors.Is(err, ErrDetached) {
+		print_detached(args[0])
+		return nil
+	}
+	return err
+}
+
+// StartAttached starts a container and prints its output until it exits, and
+// returns how it exited. ErrDetached is returned if the user detached from the
+// container using the detach keys.
+func StartAttached(container string, options AttachOptions) (*ContainerExit, error) {
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return attach(client, container, options, func() error {
+		_, err := StartSingleContainer(client, container)
+		return err
+	})
+}
+
+// attach attaches to a container, calls attached, and prints the output of the
+// container until it exits or the user detaches from it.
+func attach(client *Openapi.ClientWithResponses, container string, options AttachOptions, attached func() error) (*ContainerExit, error) {
+	detach_keys, err := ParseDetachKeys(options.DetachKeys)
+	if err != nil {
+		return nil, err
+	}
+	// The terminal is only put in raw mode when its input is forwarded, so that
+	// Ctrl-C still interrupts jcli otherwise. Input that is not typed, e.g.
+	// from a pipe, is forwarded as is.
+	raw := options.Interactive && options.TTY
+	if !raw {
+		detach_keys = nil
+	}
+	restore := func() {}
+	if raw {
+		if restore, err = MakeRawTerminal(os.Stdin); err != nil {
+			return nil, err
+		}
+	}
+	defer func() { restore() }()
+
+	done, interrupt, ws, err := Dial(fmt.Sprintf(ws_container_attach, container), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer signal.Stop(interrupt)
+	// The signals are handled before the container is started, so they never
+	// kill jcli while attached. Without --sig-proxy they disconnect like Ctrl-C.
+	if options.SigProxy {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, proxied_signals...)
+		defer signal.Stop(signals)
+		proxied := make(chan struct{})
+		go func() {
+			defer close(proxied)
+			ProxySignals(client, signals, container, done)
+		}()
+		// done is closed once the websocket is closed, which every return does.
+		defer func() { <-proxied }()
+	} else {
+		signal.Notify(interrupt, proxied_signals...)
+	}
+	sender := NewWSSender(ws)
+	var closed WSClose
+	go ListenForWSMessages(done, ws, &closed)
+	if options.TTY {
+		MonitorTerminalSize(sender, done)
+	}
+	if err = attached(); err != nil {
+		ws.Close()
+		return nil, err
+	}
+	var detached chan struct{}
+	if options.Interactive {
+		detached = make(chan struct{})
+		go func() {
+			if ForwardInput(sender, os.Stdin, detach_keys) {
+				close(detached)
+			}
+		}()
+	}
+	if err = AwaitDoneOrUserInterrupt(done, interrupt, ws, detached); err != nil {
+		return nil, err
 	}
-	container_id := args[0]
-	endpoint := fmt.Sprintf(ws_container_attach, container_id)
 
-	done, interrupt, ws := Dial(endpoint)
-	go ListenForWSMessages(done, ws)
-	StartSingleContainer(NewHTTPClient(), container_id)
-	AwaitDoneOrUserInterrupt(done, interrupt, ws)
+	// Nothing is printed by jcli while the terminal is in raw mode.
+	restore()
+	restore = func() {}
+	if closed.Err != nil {
+		return nil, &StatusError{Status: ExitEngine, Err: closed.Err}
+	}
+	if closed.ExitMessage == "" {
+		return nil, &StatusError{Status: ExitEngine, Err: errors.New("the engine closed the websocket without an exit message")}
+	}
+	fmt.Println(closed.ExitMessage)
+	return AttachedContainerExit(closed.ExitMessage), nil
+}
 
+// ProxySignals stops the container every time jcli receives a signal, until
+// done is closed. The engine closes the websocket with the exit of the
+// container once it has stopped.
+func ProxySignals(client *Openapi.ClientWithResponses, signals <-chan os.Signal, container string, done <-chan struct{}) {
+	for {
+		select {
+		case sig := <-signals:
+			debug_log("received %s, stopping container %s", sig, container)
+			// root_ctx is cancelled by SIGTERM, so the request is not based on it.
+			_, err := stop_container(context.Background(), client, container)
+			var engine_error *EngineError
+			if err != nil && !(errors.As(err, &engine_error) && engine_error.StatusCode == http.StatusNotModified) {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		case <-done:
+			return
+		}
+	}
 }
 
-func StartSingleContainer(client *Openapi.ClientWithResponses, container string) string {
-	response, err := client.ContainerStartWithResponse(context.TODO(), container)
-	status_code := response.StatusCode()
-	switch {
-	case err != nil:
-		fmt.Println("error requesting container start: ", err)
+func ContainerAttachCommand() *cobra.Command {
+	var options AttachOptions
 
-	case status_code == 200 && response.JSON200 == nil:
-		fmt.Println("could not parse jocker engine response")
+	cmd := &cobra.Command{
+		Use:                   "attach [OPTIONS] CONTAINER",
+		Short:                 "Attach to a running container",
+		Long:                  "Attach to the output of a running container, and to its input with --interactive, until it exits or jcli is detached",
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.Validate(); err != nil {
+				return err
+			}
+			_, err := AttachToContainer(args[0], options)
+			if errors.Is(err, ErrDetached) {
+				print_detached(args[0])
+				return nil
+			}
+			return err
+		},
+	}
+	add_attach_flags(cmd.Flags(), &options)
+	return cmd
+}
+
+// AttachToContainer attaches to a running container and prints its output
+// until it exits, and returns how it exited. ErrDetached is returned if the
+// user detached from the container using the detach keys.
+func AttachToContainer(name_or_id string, options AttachOptions) (*ContainerExit, error) {
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	container, err := ResolveContainer(client, name_or_id)
+	if err != nil {
+		return nil, err
+	}
+	not_running := fmt.Errorf("cannot attach to container %s, since it is not running", *container.Name)
+	if !*container.Running {
+		return nil, not_running
+	}
+	return attach(client, *container.Id, options, func() error {
+		// The container is checked again once attached, since an exit in
+		// the meantime is not reported on the websocket.
+		current, err := ResolveContainer(client, *container.Id)
+		if err != nil {
+			return err
+		}
+		if !*current.Running {
+			return not_running
+		}
+		return nil
+	})
+}
 
-	case status_code == 200:
-		return response.JSON200.Id
+// ResolveContainer returns the container with the exact ID or name, or else
+// the container whose ID starts with name_or_id, like the engine does.
+func ResolveContainer(client *Openapi.ClientWithResponses, name_or_id string) (*Openapi.ContainerSummary, error) {
+	ctx, cancel := RequestContext()
+	defer cancel()
 
-	case status_code == 304:
-		fmt.Println("container already started")
+	all := true
+	response, err := client.ContainerListWithResponse(ctx, &Openapi.ContainerListParams{All: &all})
+	if err = verify_response("container list", response, 200, err); err != nil {
+		return nil, err
+	}
 
-	case status_code == 404:
-		fmt.Println("no such container")
+	var matches []Openapi.ContainerSummary
+	for _, container := range *response.JSON200 {
+		if *container.Id == name_or_id || *container.Name == name_or_id {
+			return &container, nil
+		}
+		if strings.HasPrefix(*container.Id, name_or_id) {
+			matches = append(matches, container)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no such container: %s", name_or_id)
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, fmt.Errorf("container ID prefix '%s' is ambiguous, it matches %d containers", name_or_id, len(matches))
+}
 
-	case status_code == 500:
-		fmt.Println("internal server error")
+func StartSingleContainer(client *Openapi.ClientWithResponses, container string) (string, error) {
+	ctx, cancel := RequestContext()
+	defer cancel()
 
-	default:
-		fmt.Println("unknown status-code received from jocker engine: ", response.Status())
+	response, err := client.ContainerStartWithResponse(ctx, container)
+	if err = verify_response("container start", response, 200, err); err != nil {
+		return "", err
 	}
-	return ""
+	if response.JSON200 == nil {
+		return "", errors.New("could not parse jocker engine response")
+	}
+	return response.JSON200.Id, nil
 }
 
 func ContainerStopCommand() *cobra.Command {
+	var parallel int
+
 	cmd := &cobra.Command{
-		Use:                   "stop",
+		Use:                   "stop [OPTIONS] CONTAINER [CONTAINER...]",
 		Short:                 "Stop one or more running containers",
 		Args:                  cobra.MinimumNArgs(1),
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			response, err := ContainerStop(args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate_parallel(parallel); err != nil {
+				return err
+			}
+			client, err := NewHTTPClient()
 			if err != nil {
-				fmt.Println(response.JSON200.Id)
+				return err
 			}
+			responses, errs := StopContainers(client, args, parallel)
+			return print_results(errs, func(idx int) Openapi.IdResponse { return *responses[idx].JSON200 })
 		},
 	}
+	cmd.Flags().IntVar(&parallel, "parallel", default_parallel, "Maximum number of containers to stop concurrently")
 	return cmd
 }
 
-func ContainerStop(args []string) (*Openapi.ContainerStopResponse, error) {
-	name_or_id := args[0]
-	client := NewHTTPClient()
-	response, err := client.ContainerStopWithResponse(context.TODO(), name_or_id)
-	if err != nil {
-		return response, err
-	}
-
-	status_code := response.StatusCode()
-
-	switch {
-	case status_code == 200:
-		return response, nil
-
-	case status_code == 304:
-		return response, errors.New("container already stopped")
+// StopContainers stops every container, with at most parallel requests to
+// the engine at a time. The responses and errors are in the order of name_or_ids.
+func StopContainers(client *Openapi.ClientWithResponses, name_or_ids []string, parallel int) ([]*Openapi.ContainerStopResponse, []error) {
+	errs := make([]error, len(name_or_ids))
+	responses := make([]*Openapi.ContainerStopResponse, len(name_or_ids))
+	run_parallel(len(name_or_ids), parallel, func(idx int) {
+		responses[idx], errs[idx] = ContainerStop(client, name_or_ids[idx])
+	})
+	return responses, errs
+}
 
-	case status_code == 404:
-		return response, errors.New("no such container")
+func ContainerStop(client *Openapi.ClientWithResponses, container string) (*Openapi.ContainerStopResponse, error) {
+	return stop_container(root_ctx, client, container)
+}
 
-	case status_code == 500:
-		return response, errors.New("internal server error")
+func stop_container(parent context.Context, client *Openapi.ClientWithResponses, container string) (*Openapi.ContainerStopResponse, error) {
+	ctx, cancel := RequestContextFrom(parent)
+	defer cancel()
 
-	default:
-		return response, errors.New("unknown status-code received from jocker engine: " + string(response.Status()))
-	}
+	response, err := client.ContainerStopWithResponse(ctx, container)
+	err = verify_response("container stop", response, 200, err)
+	return response, err
 }
 
 func ContainerListCommand() *cobra.Command {
 	var all bool
+	var quiet bool
+	var latest bool
+	var last int
+	var filters []string
 
 	listCmd := &cobra.Command{
-		Use:                   "ls",
+		Use:                   "ls [OPTIONS]",
 		Short:                 "List containers",
 		Long:                  `List containers`,
 		Args:                  cobra.NoArgs,
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			response, err := GetContainerList(all)
-			if err == nil {
-				PrintContainerList(response.JSON200)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := ParseContainerFilter(filters)
+			if err != nil {
+				return err
+			}
+			if latest {
+				last = 1
+			}
+			if last < 0 {
+				return fmt.Errorf("invalid value for --last: %d", last)
 			}
+
+			containers, err := ListContainers(all || last > 0 || filter.IncludesStopped(), filter)
+			if err != nil {
+				return err
+			}
+			if last > 0 {
+				containers = LastCreated(containers, last)
+			}
+			if quiet {
+				ids := make([]string, len(containers))
+				for i, container := range containers {
+					ids[i] = *container.Id
+				}
+				return PrintQuiet(ids)
+			}
+			return PrintOutput(&containers, func() error { return PrintContainerList(&containers) })
 		},
 	}
-	listCmd.Flags().BoolVarP(&all, "all", "a", false, "Show all containers (default shows just running)")
+	flags := listCmd.Flags()
+	flags.BoolVarP(&all, "all", "a", false, "Show all containers (default shows just running)")
+	flags.StringArrayVarP(&filters, "filter", "f", []string{}, "Filter output based on conditions provided, e.g. 'name=ci-*' or 'since=2 hours'")
+	flags.IntVarP(&last, "last", "n", 0, "Show n last created containers (includes all states)")
+	flags.BoolVarP(&latest, "latest", "l", false, "Show the latest created container (includes all states)")
+	flags.BoolVarP(&quiet, "quiet", "q", false, "Only display container IDs")
 	return listCmd
 }
 
+// ListContainers returns the containers that match the filter.
+func ListContainers(all bool, filter *ContainerFilter) ([]Openapi.ContainerSummary, error) {
+	response, err := GetContainerList(all)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(*response.JSON200), nil
+}
+
 func GetContainerList(all bool) (*Openapi.ContainerListResponse, error) {
 	params := Openapi.ContainerListParams{
 		All: &all,
 	}
 
-	client := NewHTTPClient()
-
-	response, err := client.ContainerListWithResponse(context.TODO(), &params)
+	client, err := NewHTTPClient()
 	if err != nil {
-		fmt.Println("Could not connect to jocker engine daemon: ", err)
-		return response, err
+		return nil, err
 	}
+	ctx, cancel := RequestContext()
+	defer cancel()
 
-	if response.StatusCode() != 200 {
-		fmt.Println("Jocker engine returned non-200 statuscode: ", response.Status())
-		return response, errors.New("non-200 statuscode")
-	}
-	return response, nil
+	response, err := client.ContainerListWithResponse(ctx, &params)
+	err = verify_response("container list", response, 200, err)
+	return response, err
 }
 
-func PrintContainerList(container_list *[]Openapi.ContainerSummary) {
-	fmt.Println(
-		Cell("CONTAINER ID", 12), Sp(3),
-		Cell("IMAGE", 15), Sp(3),
-		Cell("COMMAND", 23), Sp(3),
-		Cell("CREATED", 18), Sp(3),
-		Cell("STATUS", 7), Sp(3),
-		"NAME",
-	)
+func PrintContainerList(container_list *[]Openapi.ContainerSummary) error {
+	table := Table{Columns: []Column{
+		{Name: "id", Header: "CONTAINER ID"},
+		{Name: "image", Header: "IMAGE"},
+		{Name: "command", Header: "COMMAND"},
+		{Name: "created", Header: "CREATED"},
+		{Name: "status", Header: "STATUS"},
+		{Name: "name", Header: "NAME"},
+	}}
 
 	var running string
 
@@ -309,16 +758,19 @@ func PrintContainerList(container_list *[]Openapi.ContainerSummary) {
 		} else {
 			running = "stopped"
 		}
-		created, _ := time.Parse(time.RFC3339, *ws.Created)
-		since_created := time.Since(created)
-
-		fmt.Println(
-			Cell(*ws.Id, 12), Sp(1),
-			Cell(*ws.ImageId, 15), Sp(1),
-			Cell(*ws.Command, 23), Sp(1),
-			Cell(HumanDuration(since_created)+" ago", 18), Sp(1),
-			Cell(running, 7), Sp(1),
-			*ws.Name,
-		)
+
+		table.AddRow(Row{
+			Cells: map[string]string{
+				"id":      *ws.Id,
+				"image":   *ws.ImageId,
+				"command": *ws.Command,
+				"created": created_ago(ws.Created),
+				"status":  running,
+				"name":    *ws.Name,
+			},
+			Name:    *ws.Name,
+			Created: created_time(ws.Created),
+		})
 	}
+	return table.Print()
 }
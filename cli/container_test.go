@@ -2,12 +2,17 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	Openapi "jcli/client"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 
 	"gotest.tools/assert"
 )
@@ -58,9 +63,309 @@ func TestContainerReferencingFunctionality(t *testing.T) {
 	t.Run("removed container", SuccesfullyRemoveContainer(t, container_id))
 }
 
+func TestContainerStopAndRemoveSeveral(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+	container_ids := make([]string, 3)
+	for i := range container_ids {
+		t.Run("create a container that sleeps when started", SuccesfullyCreateContainer(t, &container_ids[i], fmt.Sprintf("several%d", i), "base", []string{"/bin/sleep", "10"}))
+	}
+	_, err := StartSeveralContainers(container_ids[:2])
+	assert.NilError(t, err)
+
+	t.Run("every container is stopped, and failures are reported", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "stop", "--parallel", "2", container_ids[0], container_ids[2], container_ids[1])
+		assert.Equal(t, stdout, container_ids[0]+"\n"+container_ids[1]+"\n")
+		assert.Equal(t, ExitCode(err), ExitEngine)
+		VerifyStoppedContainer(container_ids[0])(t)
+		VerifyStoppedContainer(container_ids[1])(t)
+	})
+	t.Run("every container is removed, and failures are reported", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "rm", container_ids[0], "nonexisting", container_ids[1], container_ids[2])
+		assert.Equal(t, stdout, container_ids[0]+"\n"+container_ids[1]+"\n"+container_ids[2]+"\n")
+		assert.Equal(t, ExitCode(err), ExitEngine)
+		ContainerListExpectEmptyListing(true)(t)
+	})
+	t.Run("--parallel must be positive", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "rm", "--parallel", "0", "nonexisting")
+		assert.ErrorContains(t, err, "invalid value for --parallel")
+	})
+}
+
+func TestContainerForceRemove(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+	var running_id, stopped_id string
+	t.Run("create a container that sleeps when started", SuccesfullyCreateContainer(t, &running_id, "forced", "base", []string{"/bin/sleep", "10"}))
+	t.Run("create a container that is not started", SuccesfullyCreateContainer(t, &stopped_id, "unforced", "base", []string{"/bin/ls"}))
+	t.Run("start container", StartContainer(running_id, false))
+	t.Run("a running container is not removed without --force", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "rm", running_id)
+		assert.Equal(t, ExitCode(err), ExitEngine)
+		VerifyRunningContainer(running_id)(t)
+	})
+	t.Run("--force stops running containers and removes stopped containers", ExpectOutput(running_id+"\n"+stopped_id+"\n", "container", "rm", "-f", running_id, stopped_id))
+	t.Run("both are removed", ContainerListExpectEmptyListing(true))
+	t.Run("unknown containers are reported", func(t *testing.T) {
+		_, err := ForceRemoveContainer(context.Background(), NewTestClient(t), running_id)
+		ExpectEngineError(t, err, "container remove", http.StatusNotFound)
+	})
+}
+
+func TestContainerRun(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+
+	t.Run("the output of the container is printed", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "run", "--name", "runner", "base", "/bin/echo", "hello")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "runner")
+		assert.Equal(t, stdout, fmt.Sprintf("hello\ncontainer %s stopped\n", container_id))
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	})
+	t.Run("--rm removes the container when it exits", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "run", "--rm", "base", "/usr/bin/true")
+		assert.NilError(t, err)
+		ContainerListExpectEmptyListing(true)(t)
+	})
+	t.Run("--detach prints the ID of the started container", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "run", "-d", "--name", "detached", "base", "/bin/sleep", "10")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "detached")
+		assert.Equal(t, stdout, container_id+"\n")
+		VerifyRunningContainer(container_id)(t)
+		_, err = ForceRemoveContainer(context.Background(), NewTestClient(t), container_id)
+		assert.NilError(t, err)
+	})
+	t.Run("--rm cannot be used with --detach", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "run", "-d", "--rm", "base", "/bin/sleep", "10")
+		assert.ErrorContains(t, err, "--rm cannot be used with --detach")
+		ContainerListExpectEmptyListing(true)(t)
+	})
+	t.Run("--rm removes the container when jcli is interrupted", func(t *testing.T) {
+		if fake_engine == nil {
+			t.Skip("the signal is sent once the fake engine reports the container as running")
+		}
+		go SignalWhenRunning(t, "interrupted", syscall.SIGINT)
+		_, err := ExecuteRootCommand("container", "run", "--rm", "--name", "interrupted", "base", "/bin/sleep", "10")
+		assert.ErrorContains(t, err, "interrupted by user")
+		ContainerListExpectEmptyListing(true)(t)
+	})
+}
+
+func TestContainerInteractive(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+
+	t.Run("stdin is forwarded with --interactive", WithStdin("hello\nworld\n", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "run", "-i", "--name", "interactive", "base", "/bin/cat")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "interactive")
+		assert.Equal(t, stdout, fmt.Sprintf("hello\nworld\ncontainer %s stopped\n", container_id))
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	}))
+	t.Run("stdin is forwarded when starting a container", WithStdin("hello\n", func(t *testing.T) {
+		var container_id string
+		SuccesfullyCreateContainer(t, &container_id, "interactive", "base", []string{"/bin/cat"})(t)
+		stdout, err := ExecuteRootCommand("container", "start", "-i", container_id)
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, fmt.Sprintf("hello\ncontainer %s stopped\n", container_id))
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	}))
+	t.Run("--tty without --interactive does not put the terminal in raw mode", WithFakeTerminal(func(t *testing.T) {
+		make_raw = func(*os.File) (func(), error) {
+			t.Error("the terminal was put in raw mode")
+			return func() {}, nil
+		}
+		stdout, err := ExecuteRootCommand("container", "run", "-t", "--name", "tty", "base", "/bin/echo", "hello")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "tty")
+		assert.Equal(t, stdout, fmt.Sprintf("hello\ncontainer %s stopped\n", container_id))
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	}))
+	t.Run("the terminal is restored before the exit message is printed", WithFakeTerminal(WithStdin("hello\n", func(t *testing.T) {
+		make_raw = func(*os.File) (func(), error) {
+			return func() { fmt.Print("[restored]") }, nil
+		}
+		stdout, err := ExecuteRootCommand("container", "run", "-it", "--name", "tty", "base", "/bin/cat")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "tty")
+		assert.Equal(t, stdout, fmt.Sprintf("hello\n[restored]container %s stopped\n", container_id))
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	})))
+	t.Run("--tty requires a terminal", WithStdin("", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "run", "-it", "base", "/bin/cat")
+		assert.ErrorContains(t, err, "the input device is not a TTY")
+	}))
+	t.Run("nothing is left behind", ContainerListExpectEmptyListing(true))
+}
+
+const test_detach_keys_config = `
+detach-keys: ctrl-x,q
+`
+
+func TestContainerDetach(t *testing.T) {
+	config_home := t.TempDir()
+	SetEnvForTest(t, "XDG_CONFIG_HOME", config_home)
+
+	t.Run("the detach keys leave the container running", WithFakeTerminal(WithStdin("hello\n\x10\x11", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "run", "-it", "--name", "detached", "base", "/bin/cat")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "detached")
+		VerifyRunningContainer(container_id)(t)
+		_, err = ForceRemoveContainer(context.Background(), NewTestClient(t), container_id)
+		assert.NilError(t, err)
+	})))
+	t.Run("an incomplete detach sequence is sent to the container", WithFakeTerminal(WithStdin("a\x10b\n", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "run", "-it", "--name", "attached", "base", "/bin/cat")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "attached")
+		assert.Equal(t, stdout, fmt.Sprintf("a\x10b\ncontainer %s stopped\n", container_id))
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	})))
+	t.Run("the detach keys are not read from input that is not a terminal", WithStdin("a\x10\x11b\n", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "run", "-i", "--name", "piped", "base", "/bin/cat")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "piped")
+		assert.Equal(t, stdout, fmt.Sprintf("a\x10\x11b\ncontainer %s stopped\n", container_id))
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	}))
+	t.Run("the detach keys can be given with --detach-keys", WithFakeTerminal(WithStdin("\x10\x11\x18q", func(t *testing.T) {
+		var container_id string
+		SuccesfullyCreateContainer(t, &container_id, "detached", "base", []string{"/bin/cat"})(t)
+		_, err := ExecuteRootCommand("container", "start", "-it", "--detach-keys", "ctrl-x,q", container_id)
+		assert.NilError(t, err)
+		VerifyRunningContainer(container_id)(t)
+		_, err = ForceRemoveContainer(context.Background(), NewTestClient(t), container_id)
+		assert.NilError(t, err)
+	})))
+	t.Run("the detach keys can be set in the config file", WithFakeTerminal(WithStdin("\x18q", func(t *testing.T) {
+		assert.NilError(t, os.MkdirAll(filepath.Join(config_home, "jcli"), 0755))
+		assert.NilError(t, os.WriteFile(filepath.Join(config_home, "jcli", "config.yaml"), []byte(test_detach_keys_config), 0644))
+		defer os.Remove(filepath.Join(config_home, "jcli", "config.yaml"))
+		_, err := ExecuteRootCommand("container", "run", "-it", "--name", "detached", "base", "/bin/sleep", "10")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "detached")
+		VerifyRunningContainer(container_id)(t)
+		_, err = ForceRemoveContainer(context.Background(), NewTestClient(t), container_id)
+		assert.NilError(t, err)
+	})))
+	t.Run("invalid detach keys are rejected", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "run", "--detach-keys", "ctrl-1", "base", "/bin/cat")
+		assert.ErrorContains(t, err, "invalid detach key 'ctrl-1'")
+	})
+	t.Run("nothing is left behind", ContainerListExpectEmptyListing(true))
+}
+
+func TestContainerAttach(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+	var container_id string
+	t.Run("create a container that echoes its input", SuccesfullyCreateContainer(t, &container_id, "reattached", "base", []string{"/bin/cat"}))
+
+	t.Run("attaching to a stopped container is refused", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "attach", "reattached")
+		assert.ErrorContains(t, err, "cannot attach to container reattached, since it is not running")
+	})
+	t.Run("attaching to an unknown container is refused", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "attach", "nonexisting")
+		assert.ErrorContains(t, err, "no such container: nonexisting")
+	})
+	t.Run("start container", StartContainer(container_id, false))
+	t.Run("attach using the ID prefix and detach", WithFakeTerminal(WithStdin("\x10\x11", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "attach", "-it", container_id[:4])
+		assert.NilError(t, err)
+		VerifyRunningContainer(container_id)(t)
+	})))
+	t.Run("attach until the container exits", WithStdin("hello\n", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "attach", "-i", "reattached")
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, fmt.Sprintf("hello\ncontainer %s stopped\n", container_id))
+		VerifyStoppedContainer(container_id)(t)
+	}))
+	t.Run("remove container", SuccesfullyRemoveContainer(t, container_id))
+}
+
+func TestContainerSignalProxy(t *testing.T) {
+	if fake_engine == nil {
+		t.Skip("the signal is sent once the fake engine reports the container as running")
+	}
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+
+	t.Run("SIGTERM stops the container", func(t *testing.T) {
+		go SignalWhenRunning(t, "proxied", syscall.SIGTERM)
+		stdout, err := ExecuteRootCommand("container", "run", "--name", "proxied", "base", "/bin/sleep", "10")
+		assert.NilError(t, err)
+		container_id := ContainerIdOf(t, "proxied")
+		assert.Equal(t, stdout, fmt.Sprintf("container %s stopped\n", container_id))
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	})
+	t.Run("SIGHUP stops an attached container", func(t *testing.T) {
+		var container_id string
+		SuccesfullyCreateContainer(t, &container_id, "proxied", "base", []string{"/bin/sleep", "10"})(t)
+		go SignalWhenRunning(t, "proxied", syscall.SIGHUP)
+		_, err := ExecuteRootCommand("container", "start", "-a", "proxied")
+		assert.NilError(t, err)
+		VerifyStoppedContainer(container_id)(t)
+		SuccesfullyRemoveContainer(t, container_id)(t)
+	})
+	t.Run("--sig-proxy=false disconnects and leaves the container running", func(t *testing.T) {
+		go SignalWhenRunning(t, "unproxied", syscall.SIGTERM)
+		_, err := ExecuteRootCommand("container", "run", "--sig-proxy=false", "--name", "unproxied", "base", "/bin/sleep", "10")
+		assert.ErrorContains(t, err, "interrupted by user")
+		container_id := ContainerIdOf(t, "unproxied")
+		VerifyRunningContainer(container_id)(t)
+		_, err = ForceRemoveContainer(context.Background(), NewTestClient(t), container_id)
+		assert.NilError(t, err)
+	})
+	t.Run("nothing is left behind", ContainerListExpectEmptyListing(true))
+}
+
+// SignalWhenRunning sends sig to jcli once the container is running, i.e.,
+// after jcli has attached to it.
+func SignalWhenRunning(t *testing.T, name string, sig syscall.Signal) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !fake_engine.IsRunning(name) {
+		if time.Now().After(deadline) {
+			t.Errorf("container %s was not started", name)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := syscall.Kill(os.Getpid(), sig); err != nil {
+		t.Errorf("sending %s: %s", sig, err)
+	}
+}
+
+// WithStdin replaces stdin with a pipe containing input while running f.
+func WithStdin(input string, f func(*testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		r, w, err := os.Pipe()
+		assert.NilError(t, err)
+		_, err = w.WriteString(input)
+		assert.NilError(t, err)
+		w.Close()
+		old_stdin := os.Stdin
+		os.Stdin = r
+		defer func() {
+			os.Stdin = old_stdin
+			r.Close()
+		}()
+		f(t)
+	}
+}
+
+// ContainerIdOf returns the ID of the container with the given name.
+func ContainerIdOf(t *testing.T, name string) string {
+	response, err := GetContainerList(true)
+	assert.NilError(t, err)
+	for _, container := range *response.JSON200 {
+		if *container.Name == name {
+			return *container.Id
+		}
+	}
+	t.Fatalf("container %s does not exist", name)
+	return ""
+}
+
 func StopContainer(container_id string) func(t *testing.T) {
 	return func(t *testing.T) {
-		response, err := ContainerStop([]string{container_id})
+		response, err := ContainerStop(NewTestClient(t), container_id)
 		assert.NilError(t, err)
 		assert.Equal(t, len(response.JSON200.Id), 12)
 	}
@@ -69,9 +374,11 @@ func StopContainer(container_id string) func(t *testing.T) {
 func StartContainer(container_id string, attach bool) func(*testing.T) {
 	return func(t *testing.T) {
 		if attach {
-			StartAndAttachToContainer([]string{container_id})
+			err := StartAndAttachToContainer([]string{container_id}, AttachOptions{})
+			assert.NilError(t, err)
 		} else {
-			container_ids := StartSeveralContainers([]string{container_id})
+			container_ids, err := StartSeveralContainers([]string{container_id})
+			assert.NilError(t, err)
 			container_id_returned := container_ids[0]
 			assert.Equal(t, len(container_id_returned), 12)
 		}
@@ -80,6 +387,10 @@ func StartContainer(container_id string, attach bool) func(*testing.T) {
 
 func VerifyStoppedContainer(container_id string) func(*testing.T) {
 	return func(t *testing.T) {
+		if fake_engine != nil {
+			assert.Assert(t, !fake_engine.IsRunning(container_id))
+			return
+		}
 		cmd := exec.Command("/bin/sh", "-c", "jls | grep "+container_id)
 		cmd.Run()
 		output, _ := cmd.Output()
@@ -89,6 +400,10 @@ func VerifyStoppedContainer(container_id string) func(*testing.T) {
 
 func VerifyRunningContainer(container_id string) func(t *testing.T) {
 	return func(t *testing.T) {
+		if fake_engine != nil {
+			assert.Assert(t, fake_engine.IsRunning(container_id))
+			return
+		}
 		cmd := exec.Command("/bin/sh", "-c", "jls | grep "+container_id)
 		err := cmd.Run()
 		// If the container exists, grepping after the id will result in non-empty output from grep
@@ -119,8 +434,7 @@ func ContainerListExpectContainer(all bool, container_name string) func(*testing
 
 func SuccesfullyRemoveContainer(t *testing.T, container_id string) func(*testing.T) {
 	return func(t *testing.T) {
-		args := []string{container_id}
-		response, err := PostContainerRemove(args)
+		response, err := PostContainerRemove(NewTestClient(t), container_id)
 		assert.NilError(t, err)
 		var empty_id_response *Openapi.IdResponse
 		assert.Assert(t, empty_id_response != response.JSON200)
@@ -0,0 +1,398 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// The 'default' context is not stored. It represents the engine given by
+// --host or the configuration, falling back to the default host.
+const default_context = "default"
+
+// EngineContext is a named profile describing how to connect to an engine.
+type EngineContext struct {
+	Name             string   `json:"name"`
+	Description      string   `json:"description,omitempty"`
+	Host             string   `json:"host"`
+	TLS              bool     `json:"tls,omitempty"`
+	TLSCACert        string   `json:"tls_ca_cert,omitempty"`
+	TLSCert          string   `json:"tls_cert,omitempty"`
+	TLSKey           string   `json:"tls_key,omitempty"`
+	TLSVerify        bool     `json:"tls_verify,omitempty"`
+	Token            string   `json:"token,omitempty"`
+	AuthScheme       string   `json:"auth_scheme,omitempty"`
+	CredentialHelper string   `json:"credential_helper,omitempty"`
+	Networks         []string `json:"networks,omitempty"`
+}
+
+// Redact hides the token of the context, so that it can be printed.
+func (c *EngineContext) Redact() {
+	if c.Token != "" {
+		c.Token = "[REDACTED]"
+	}
+}
+
+// ContextStore holds every context created with 'jcli context create' and
+// the name of the context selected with 'jcli context use'.
+type ContextStore struct {
+	Current  string                   `json:"current,omitempty"`
+	Contexts map[string]EngineContext `json:"contexts"`
+}
+
+func ContextStorePath() string {
+	return filepath.Join(ConfigDir(), "contexts.json")
+}
+
+// LoadContextStore reads the context store. A missing store is treated as an empty one.
+func LoadContextStore() (*ContextStore, error) {
+	store := &ContextStore{Contexts: map[string]EngineContext{}}
+	data, err := ioutil.ReadFile(ContextStorePath())
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("could not parse context store %s: %w", ContextStorePath(), err)
+	}
+	if store.Contexts == nil {
+		store.Contexts = map[string]EngineContext{}
+	}
+	return store, nil
+}
+
+// Save writes the context store. It is only readable by the owner since the
+// contexts can contain authentication tokens.
+func (store *ContextStore) Save() error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(ContextStorePath()), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(ContextStorePath(), data, 0600)
+}
+
+func (store *ContextStore) Get(name string) (*EngineContext, error) {
+	engine_context, exists := store.Contexts[name]
+	if !exists {
+		return nil, fmt.Errorf("context '%s' does not exist", name)
+	}
+	return &engine_context, nil
+}
+
+// ActiveContext returns the context that is used for requests to the engine:
+//  1. the context given with --context (or JCLI_CONTEXT), which cannot be combined with --host,
+//  2. the host given with --host (or JCLI_HOST/the config file),
+//  3. the context selected with 'jcli context use',
+//  4. the default host.
+func ActiveContext() (*EngineContext, error) {
+	if context_name == default_context {
+		return DefaultContext(), nil
+	}
+	if context_name != "" {
+		// A host from JCLI_HOST or the config file is overridden by --context.
+		if host_flag_given() {
+			return nil, errors.New("conflicting options: either specify --host or --context, not both")
+		}
+		store, err := LoadContextStore()
+		if err != nil {
+			return nil, err
+		}
+		return store.Get(context_name)
+	}
+	if host != "" {
+		return DefaultContext(), nil
+	}
+
+	store, err := LoadContextStore()
+	if err != nil {
+		return nil, err
+	}
+	if store.Current == "" {
+		return DefaultContext(), nil
+	}
+	return store.Get(store.Current)
+}
+
+// host_flag_given returns true if --host was given on the command line, as
+// opposed to JCLI_HOST or the config file.
+func host_flag_given() bool {
+	flag := RootCmd.PersistentFlags().Lookup("host")
+	return flag != nil && flag.Changed
+}
+
+// DefaultContext returns the context described by the global flags. The
+// credentials can only be set in the configuration (or JCLI_TOKEN etc.) to
+// avoid exposing them on the command line.
+func DefaultContext() *EngineContext {
+	return &EngineContext{
+		Name:             default_context,
+		Host:             host,
+		TLS:              use_tls,
+		TLSCACert:        tls_cacert,
+		TLSCert:          tls_cert,
+		TLSKey:           tls_key,
+		TLSVerify:        tls_verify,
+		Token:            settings.GetString("token"),
+		AuthScheme:       settings.GetString("auth-scheme"),
+		CredentialHelper: settings.GetString("credential-helper"),
+	}
+}
+
+func ContextCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "context",
+		Short:                 "Manage engine contexts",
+		Long:                  `Manage named engine contexts, each describing how to connect to a jocker engine`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(ContextCreateCommand())
+	cmd.AddCommand(ContextListCommand())
+	cmd.AddCommand(ContextUseCommand())
+	cmd.AddCommand(ContextRemoveCommand())
+	cmd.AddCommand(ContextInspectCommand())
+	return cmd
+}
+
+func ContextCreateCommand() *cobra.Command {
+	engine_context := EngineContext{}
+
+	cmd := &cobra.Command{
+		Use:                   "create [OPTIONS] CONTEXT",
+		Short:                 "Create a context",
+		Long:                  `Create a context`,
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			engine_context.Name = args[0]
+			if err := ContextCreate(engine_context); err != nil {
+				return fmt.Errorf("could not create context: %w", err)
+			}
+			fmt.Println(engine_context.Name)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&engine_context.Description, "description", "", "Description of the context")
+	flags.StringVar(&engine_context.Host, "host", "", "Daemon socket to connect to: tcp://[host]:[port][path] or unix://[/path/to/socket]")
+	flags.BoolVar(&engine_context.TLS, "tls", false, "Use TLS when connecting to the engine")
+	flags.StringVar(&engine_context.TLSCACert, "tlscacert", "", "Trust certs signed only by this CA")
+	flags.StringVar(&engine_context.TLSCert, "tlscert", "", "Path to TLS certificate file")
+	flags.StringVar(&engine_context.TLSKey, "tlskey", "", "Path to TLS key file")
+	flags.BoolVar(&engine_context.TLSVerify, "tlsverify", false, "Use TLS and verify the remote")
+	flags.StringVar(&engine_context.Token, "token", "", "Token used to authenticate with the engine")
+	flags.StringVar(&engine_context.AuthScheme, "auth-scheme", default_auth_scheme, "Scheme used in the Authorization header, e.g. 'Bearer' or 'ApiKey'")
+	flags.StringVar(&engine_context.CredentialHelper, "credential-helper", "", "Executable that provides the token when no token is given")
+	flags.StringSliceVar(&engine_context.Networks, "network", []string{}, "Networks that new containers are connected to when none are specified")
+	return cmd
+}
+
+func ContextCreate(engine_context EngineContext) error {
+	if engine_context.Name == default_context {
+		return errors.New("'default' is a reserved context name")
+	}
+	if _, err := ParseEndpoint(engine_context.Host); err != nil {
+		return err
+	}
+
+	store, err := LoadContextStore()
+	if err != nil {
+		return err
+	}
+	if _, exists := store.Contexts[engine_context.Name]; exists {
+		return fmt.Errorf("context '%s' already exists", engine_context.Name)
+	}
+	store.Contexts[engine_context.Name] = engine_context
+	return store.Save()
+}
+
+func ContextListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "ls",
+		Short:                 "List contexts",
+		Long:                  `List contexts. The context currently in use is marked with '*'`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := LoadContextStore()
+			if err != nil {
+				return fmt.Errorf("could not read contexts: %w", err)
+			}
+			return PrintContextList(store)
+		},
+	}
+	return cmd
+}
+
+func PrintContextList(store *ContextStore) error {
+	names := make([]string, 0, len(store.Contexts))
+	for name := range store.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	current := func(name string) string {
+		if name == store.Current || (name == default_context && store.Current == "") {
+			return name + " *"
+		}
+		return name
+	}
+
+	default_context_host := host
+	if default_context_host == "" {
+		default_context_host = default_host
+	}
+
+	table := Table{Columns: []Column{
+		{Name: "name", Header: "NAME"},
+		{Name: "description", Header: "DESCRIPTION"},
+		{Name: "host", Header: "HOST"},
+	}}
+	table.AddRow(Row{
+		Cells: map[string]string{"name": current(default_context), "description": "Settings from flags and configuration", "host": default_context_host},
+		Name:  default_context,
+	})
+	for _, name := range names {
+		engine_context := store.Contexts[name]
+		table.AddRow(Row{
+			Cells: map[string]string{"name": current(name), "description": engine_context.Description, "host": engine_context.Host},
+			Name:  name,
+		})
+	}
+	return table.Print()
+}
+
+func ContextUseCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "use CONTEXT",
+		Short:                 "Set the current context",
+		Long:                  `Set the current context. Use 'default' to stop using a stored context`,
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ContextUse(args[0]); err != nil {
+				return fmt.Errorf("could not change context: %w", err)
+			}
+			fmt.Println(args[0])
+			return nil
+		},
+	}
+	return cmd
+}
+
+func ContextUse(name string) error {
+	store, err := LoadContextStore()
+	if err != nil {
+		return err
+	}
+	if name == default_context {
+		store.Current = ""
+		return store.Save()
+	}
+	if _, err = store.Get(name); err != nil {
+		return err
+	}
+	store.Current = name
+	return store.Save()
+}
+
+func ContextRemoveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "rm CONTEXT [CONTEXT...]",
+		Short:                 "Remove one or more contexts",
+		Long:                  `Remove one or more contexts`,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var failed bool
+			for _, name := range args {
+				if err := ContextRemove(name); err != nil {
+					fmt.Fprintln(os.Stderr, "Error: could not remove context:", err)
+					failed = true
+					continue
+				}
+				fmt.Println(name)
+			}
+			if failed {
+				return &StatusError{Status: ExitGeneric}
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func ContextRemove(name string) error {
+	store, err := LoadContextStore()
+	if err != nil {
+		return err
+	}
+	if _, err = store.Get(name); err != nil {
+		return err
+	}
+	if store.Current == name {
+		return fmt.Errorf("context '%s' is in use, switch to another context first", name)
+	}
+	delete(store.Contexts, name)
+	return store.Save()
+}
+
+func ContextInspectCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "inspect [CONTEXT...]",
+		Short:                 "Display detailed information on one or more contexts",
+		Long:                  `Display detailed information on one or more contexts. Inspects the context in use if none is given`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			engine_contexts, err := ContextInspect(args)
+			if err != nil {
+				return fmt.Errorf("could not inspect context: %w", err)
+			}
+			for _, engine_context := range engine_contexts {
+				engine_context.Redact()
+			}
+			output, _ := json.MarshalIndent(engine_contexts, "", "  ")
+			fmt.Println(string(output))
+			return nil
+		},
+	}
+	return cmd
+}
+
+func ContextInspect(names []string) ([]*EngineContext, error) {
+	if len(names) == 0 {
+		engine_context, err := ActiveContext()
+		return []*EngineContext{engine_context}, err
+	}
+
+	store, err := LoadContextStore()
+	if err != nil {
+		return nil, err
+	}
+	engine_contexts := make([]*EngineContext, len(names))
+	for i, name := range names {
+		if name == default_context {
+			engine_contexts[i] = DefaultContext()
+			continue
+		}
+		if engine_contexts[i], err = store.Get(name); err != nil {
+			return nil, err
+		}
+	}
+	return engine_contexts, nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestContextCreateUseRemove(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+	ClearHost(t)
+	staging := EngineContext{Name: "staging", Host: "tcp://staging:8085", Networks: []string{"testnet"}}
+	ci := EngineContext{Name: "ci", Host: "unix:///var/run/jocker.sock"}
+
+	t.Run("the default context is active when no context exists", ExpectActiveContext("default", ""))
+	t.Run("create the 'staging' context", func(t *testing.T) { assert.NilError(t, ContextCreate(staging)) })
+	t.Run("create the 'ci' context", func(t *testing.T) { assert.NilError(t, ContextCreate(ci)) })
+	t.Run("contexts are only readable by the owner", func(t *testing.T) {
+		info, err := os.Stat(ContextStorePath())
+		assert.NilError(t, err)
+		assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+	})
+	t.Run("creating an existing context fails", func(t *testing.T) {
+		assert.ErrorContains(t, ContextCreate(staging), "already exists")
+	})
+	t.Run("'default' is reserved", func(t *testing.T) {
+		assert.ErrorContains(t, ContextCreate(EngineContext{Name: "default"}), "reserved")
+	})
+	t.Run("contexts with an invalid host are rejected", func(t *testing.T) {
+		assert.ErrorContains(t, ContextCreate(EngineContext{Name: "broken", Host: "ftp://staging"}), "unsupported protocol")
+	})
+	t.Run("creating a context does not make it active", ExpectActiveContext("default", ""))
+	t.Run("use the 'staging' context", func(t *testing.T) { assert.NilError(t, ContextUse("staging")) })
+	t.Run("'staging' is now active", ExpectActiveContext("staging", "tcp://staging:8085"))
+	t.Run("the endpoint is taken from the active context", func(t *testing.T) {
+		endpoint, err := CurrentEndpoint()
+		assert.NilError(t, err)
+		assert.Equal(t, endpoint.HTTPBaseURL(), "http://staging:8085")
+	})
+	t.Run("--context overrides the current context", WithContextName("ci", ExpectActiveContext("ci", "unix:///var/run/jocker.sock")))
+	t.Run("--host overrides the current context", WithHost("tcp://other:8085", ExpectActiveContext("default", "tcp://other:8085")))
+	t.Run("--context overrides a host from the environment or configuration", WithHost("tcp://other:8085", WithContextName("ci", ExpectActiveContext("ci", "unix:///var/run/jocker.sock"))))
+	t.Run("--host and --context are mutually exclusive", func(t *testing.T) {
+		_, err := ExecuteRootCommand("--host", "tcp://other:8085", "--context", "ci", "container", "ls")
+		assert.ErrorContains(t, err, "conflicting options")
+	})
+	t.Run("using an unknown context fails", func(t *testing.T) {
+		assert.ErrorContains(t, ContextUse("production"), "does not exist")
+	})
+	t.Run("an unknown --context is returned as an error, not printed on stdout", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("--context", "production", "container", "ls")
+		assert.ErrorContains(t, err, "context 'production' does not exist")
+		assert.Equal(t, stdout, "")
+	})
+	t.Run("the context in use cannot be removed", func(t *testing.T) {
+		assert.ErrorContains(t, ContextRemove("staging"), "is in use")
+	})
+	t.Run("remove the 'ci' context", func(t *testing.T) { assert.NilError(t, ContextRemove("ci")) })
+	t.Run("inspecting a removed context fails", func(t *testing.T) {
+		_, err := ContextInspect([]string{"ci"})
+		assert.ErrorContains(t, err, "does not exist")
+	})
+	t.Run("inspecting 'default' returns the default context", func(t *testing.T) {
+		engine_contexts, err := ContextInspect([]string{"default"})
+		assert.NilError(t, err)
+		assert.Equal(t, engine_contexts[0].Name, "default")
+	})
+	t.Run("the token is not printed when inspecting a context", func(t *testing.T) {
+		assert.NilError(t, ContextCreate(EngineContext{Name: "secret", Host: "tcp://secret:8085", Token: "s3cr3t"}))
+		stdout, err := ExecuteRootCommand("context", "inspect", "secret")
+		assert.NilError(t, err)
+		assert.Assert(t, !strings.Contains(stdout, "s3cr3t"))
+		assert.Assert(t, strings.Contains(stdout, `"token": "[REDACTED]"`))
+	})
+	t.Run("'jcli context' prints the help", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("context")
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(stdout, "Available Commands:"))
+	})
+	t.Run("switch back to the default context", func(t *testing.T) { assert.NilError(t, ContextUse("default")) })
+	t.Run("the default context is active again", ExpectActiveContext("default", ""))
+}
+
+func ExpectActiveContext(name, host string) func(*testing.T) {
+	return func(t *testing.T) {
+		engine_context, err := ActiveContext()
+		assert.NilError(t, err)
+		assert.Equal(t, engine_context.Name, name)
+		assert.Equal(t, engine_context.Host, host)
+	}
+}
+
+func WithContextName(name string, f func(*testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		old_context_name := context_name
+		context_name = name
+		defer func() { context_name = old_context_name }()
+		f(t)
+	}
+}
+
+func WithHost(new_host string, f func(*testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		old_host := host
+		host = new_host
+		defer func() { host = old_host }()
+		f(t)
+	}
+}
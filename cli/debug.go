@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	Openapi "jcli/client"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Where --debug output is written
+var debug_output io.Writer = os.Stderr
+
+// Headers that are never written to the debug output.
+var secret_headers = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+func debug_log(format string, args ...interface{}) {
+	if debug {
+		log.New(debug_output, "[debug] ", log.Ltime|log.Lmicroseconds).Printf(format, args...)
+	}
+}
+
+// TracingDoer is a HttpRequestDoer that writes every request and response
+// to the debug output.
+type TracingDoer struct {
+	Doer Openapi.HttpRequestDoer
+}
+
+func (d *TracingDoer) Do(req *http.Request) (*http.Response, error) {
+	request_body, err := read_and_restore(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	debug_log("--> %s %s\n%s%s", req.Method, req.URL, format_headers(req.Header), request_body)
+
+	start := time.Now()
+	response, err := d.Doer.Do(req)
+	latency := time.Since(start).Round(time.Microsecond)
+	if err != nil {
+		debug_log("<-- %s %s failed after %s: %s", req.Method, req.URL, latency, err)
+		return response, err
+	}
+
+	response_body, err := read_and_restore(&response.Body)
+	if err != nil {
+		return nil, err
+	}
+	debug_log("<-- %s (%s)\n%s%s", response.Status, latency, format_headers(response.Header), response_body)
+	return response, nil
+}
+
+func read_and_restore(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	content, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	*body = ioutil.NopCloser(bytes.NewReader(content))
+	return content, err
+}
+
+func format_headers(headers http.Header) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var formatted strings.Builder
+	for _, name := range names {
+		value := strings.Join(headers[name], ", ")
+		if is_secret_header(name) {
+			value = "[REDACTED]"
+		}
+		fmt.Fprintf(&formatted, "%s: %s\n", name, value)
+	}
+	return formatted.String()
+}
+
+func is_secret_header(name string) bool {
+	for _, secret := range secret_headers {
+		if strings.EqualFold(name, secret) {
+			return true
+		}
+	}
+	return false
+}
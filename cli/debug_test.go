@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	Openapi "jcli/client"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestDebugTracing(t *testing.T) {
+	server := httptest.NewServer(NetworkListAndAttachHandler())
+	defer server.Close()
+
+	var output bytes.Buffer
+	old_debug, old_debug_output := debug, debug_output
+	debug, debug_output = true, &output
+	defer func() { debug, debug_output = old_debug, old_debug_output }()
+
+	settings.Set("token", "very-secret-token")
+	defer settings.Set("token", "")
+
+	t.Run("requests and responses are traced", WithHost(server.URL, func(t *testing.T) {
+		output.Reset()
+		_, err := NetworkList()
+		assert.NilError(t, err)
+		trace := output.String()
+		assert.Assert(t, is.Contains(trace, "--> GET "+server.URL+"/networks/list"))
+		assert.Assert(t, is.Contains(trace, "Authorization: [REDACTED]"))
+		assert.Assert(t, is.Contains(trace, "<-- 200 OK"))
+		assert.Assert(t, is.Contains(trace, `"name": "default"`))
+		assert.Assert(t, !bytes.Contains(output.Bytes(), []byte("very-secret-token")))
+	}))
+
+	t.Run("request bodies are traced", WithHost(server.URL, func(t *testing.T) {
+		output.Reset()
+		driver := "loopback"
+		_, _ = NetworkCreate([]string{"testnet"}, Openapi.NetworkCreateJSONRequestBody{Driver: &driver})
+		trace := output.String()
+		assert.Assert(t, is.Contains(trace, "--> POST "+server.URL+"/networks/create"))
+		assert.Assert(t, is.Contains(trace, `{"driver":"loopback","name":"testnet"}`))
+		assert.Assert(t, is.Contains(trace, "<-- 404 Not Found"))
+	}))
+
+	t.Run("websocket frames are traced", WithHost(server.URL, func(t *testing.T) {
+		output.Reset()
+		done, _, ws, err := Dial("/containers/abc/attach", nil)
+		assert.NilError(t, err)
+		defer ws.Close()
+		RunCommandCollectStdOut(func() { ListenForWSMessages(done, ws, nil) })
+		trace := output.String()
+		assert.Assert(t, is.Contains(trace, "Authorization: [REDACTED]"))
+		assert.Assert(t, is.Contains(trace, "<-- websocket 101 Switching Protocols"))
+		assert.Assert(t, is.Contains(trace, `<-- websocket frame (type 1): "ok:"`))
+		assert.Assert(t, is.Contains(trace, "<-- websocket closed"))
+	}))
+}
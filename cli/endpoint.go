@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const default_host = "tcp://localhost:8085"
+const default_port = "8085"
+
+// Endpoint describes where a jocker engine is listening. The engine can be
+// reached either over tcp (Address is 'host:port') or through a unix socket
+// (Address is the path of the socket). Path is an optional base path that is
+// prepended to every API path, e.g. when the engine is behind a reverse proxy.
+// If TLSConfig is set, https and wss are used instead of http and ws.
+type Endpoint struct {
+	Scheme    string
+	Address   string
+	Path      string
+	TLSConfig *tls.Config
+}
+
+// ParseEndpoint parses a host in the format used by the --host flag:
+// tcp://[host]:[port][path] or unix://[/path/to/socket]. An empty host
+// results in the default endpoint.
+func ParseEndpoint(host string) (*Endpoint, error) {
+	if host == "" {
+		host = default_host
+	}
+	if !strings.Contains(host, "://") {
+		host = "tcp://" + host
+	}
+
+	host_url, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch host_url.Scheme {
+	case "unix":
+		socket_path := host_url.Host + host_url.Path
+		if socket_path == "" {
+			return nil, errors.New("no socket path given in " + host)
+		}
+		return &Endpoint{Scheme: "unix", Address: socket_path}, nil
+
+	case "tcp", "http":
+		address := host_url.Host
+		if address == "" {
+			return nil, errors.New("no address given in " + host)
+		}
+		if host_url.Port() == "" {
+			address = net.JoinHostPort(host_url.Hostname(), default_port)
+		}
+		return &Endpoint{Scheme: "tcp", Address: address, Path: strings.TrimSuffix(host_url.Path, "/")}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported protocol '%s' in %s", host_url.Scheme, host)
+	}
+}
+
+// CurrentEndpoint returns the endpoint of the engine in the active context.
+func CurrentEndpoint() (*Endpoint, error) {
+	engine_context, err := ActiveContext()
+	if err != nil {
+		return nil, err
+	}
+	return engine_context.Endpoint()
+}
+
+// CurrentConnection returns the endpoint and credentials of the active context.
+func CurrentConnection() (*Endpoint, *Credentials, error) {
+	engine_context, err := ActiveContext()
+	if err != nil {
+		return nil, nil, err
+	}
+	endpoint, err := engine_context.Endpoint()
+	if err != nil {
+		return nil, nil, err
+	}
+	credentials, err := engine_context.Credentials()
+	return endpoint, credentials, err
+}
+
+// Endpoint returns the endpoint of the engine that the context describes.
+func (c *EngineContext) Endpoint() (*Endpoint, error) {
+	endpoint, err := ParseEndpoint(c.Host)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.TLSConfig, err = c.TLSConfig()
+	return endpoint, err
+}
+
+// HTTPBaseURL returns the URL that the REST API paths are appended to.
+func (e *Endpoint) HTTPBaseURL() string {
+	return e.baseURL("http")
+}
+
+// WebsocketURL returns the URL of a websocket endpoint of the engine.
+func (e *Endpoint) WebsocketURL(path string, query url.Values) string {
+	ws_url := e.baseURL("ws") + path
+	if len(query) > 0 {
+		ws_url += "?" + query.Encode()
+	}
+	return ws_url
+}
+
+func (e *Endpoint) baseURL(scheme string) string {
+	if e.TLSConfig != nil {
+		scheme += "s"
+	}
+	if e.Scheme == "unix" {
+		// The host part is ignored when dialing a unix socket but it must be a valid hostname.
+		return scheme + "://localhost" + e.Path
+	}
+	return scheme + "://" + e.Address + e.Path
+}
+
+// DialContext connects to the endpoint. The address is ignored for unix sockets
+// since the socket path is part of the endpoint.
+func (e *Endpoint) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if e.Scheme == "unix" {
+		return dialer.DialContext(ctx, "unix", e.Address)
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// HTTPClient returns a http client that sends every request to the endpoint,
+// and can be used as the HttpRequestDoer of the generated client.
+func (e *Endpoint) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = e.DialContext
+	transport.TLSClientConfig = e.TLSConfig
+	if e.Scheme == "unix" {
+		transport.Proxy = nil
+	}
+	return &http.Client{Transport: transport}
+}
+
+// WebsocketDialer returns a websocket dialer that connects to the endpoint.
+func (e *Endpoint) WebsocketDialer() *websocket.Dialer {
+	dialer := &websocket.Dialer{
+		NetDialContext:   e.DialContext,
+		TLSClientConfig:  e.TLSConfig,
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 45 * time.Second,
+	}
+	if e.Scheme == "unix" {
+		dialer.Proxy = nil
+	}
+	return dialer
+}
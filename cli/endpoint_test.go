@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	t.Run("empty host gives the default endpoint", ExpectEndpoint("", Endpoint{Scheme: "tcp", Address: "localhost:8085"}))
+	t.Run("tcp host with port and path", ExpectEndpoint("tcp://10.0.0.2:9000/jocker/", Endpoint{Scheme: "tcp", Address: "10.0.0.2:9000", Path: "/jocker"}))
+	t.Run("tcp host without port uses the default port", ExpectEndpoint("tcp://enginehost", Endpoint{Scheme: "tcp", Address: "enginehost:8085"}))
+	t.Run("host without protocol defaults to tcp", ExpectEndpoint("enginehost:1234", Endpoint{Scheme: "tcp", Address: "enginehost:1234"}))
+	t.Run("unix socket", ExpectEndpoint("unix:///var/run/jocker.sock", Endpoint{Scheme: "unix", Address: "/var/run/jocker.sock"}))
+	t.Run("unsupported protocol", func(t *testing.T) {
+		_, err := ParseEndpoint("ftp://enginehost")
+		assert.ErrorContains(t, err, "unsupported protocol 'ftp'")
+	})
+	t.Run("unix socket without a path", func(t *testing.T) {
+		_, err := ParseEndpoint("unix://")
+		assert.ErrorContains(t, err, "no socket path")
+	})
+}
+
+func TestEndpointURLs(t *testing.T) {
+	tcp := Endpoint{Scheme: "tcp", Address: "enginehost:8085", Path: "/jocker"}
+	assert.Equal(t, tcp.HTTPBaseURL(), "http://enginehost:8085/jocker")
+	assert.Equal(t, tcp.WebsocketURL("/containers/abc/attach", nil), "ws://enginehost:8085/jocker/containers/abc/attach")
+
+	unix := Endpoint{Scheme: "unix", Address: "/var/run/jocker.sock"}
+	assert.Equal(t, unix.HTTPBaseURL(), "http://localhost")
+	assert.Equal(t, unix.WebsocketURL("/images/build", map[string][]string{"tag": {"test"}}), "ws://localhost/images/build?tag=test")
+}
+
+func TestUnixSocketTransport(t *testing.T) {
+	socket_path := filepath.Join(t.TempDir(), "jocker.sock")
+	listener, err := net.Listen("unix", socket_path)
+	assert.NilError(t, err)
+
+	server := httptest.NewUnstartedServer(NetworkListAndAttachHandler())
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	old_host := host
+	host = "unix://" + socket_path
+	defer func() { host = old_host }()
+
+	t.Run("REST calls are sent through the socket", func(t *testing.T) {
+		response, err := NetworkList()
+		assert.NilError(t, err)
+		assert.Equal(t, *(*response.JSON200)[0].Name, "default")
+	})
+
+	t.Run("websockets are dialed through the socket", func(t *testing.T) {
+		endpoint, err := CurrentEndpoint()
+		assert.NilError(t, err)
+		ws, _, err := endpoint.WebsocketDialer().Dial(endpoint.WebsocketURL("/containers/abc/attach", nil), nil)
+		assert.NilError(t, err)
+		defer ws.Close()
+		_, message, err := ws.ReadMessage()
+		assert.NilError(t, err)
+		assert.Equal(t, string(message), "ok:")
+	})
+}
+
+func ExpectEndpoint(host string, expected Endpoint) func(*testing.T) {
+	return func(t *testing.T) {
+		endpoint, err := ParseEndpoint(host)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, *endpoint, expected)
+	}
+}
+
+func NetworkListAndAttachHandler() http.Handler {
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/networks/list", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id": "123456789abc", "name": "default", "driver": "loopback"}]`))
+	})
+	mux.HandleFunc("/containers/abc/attach", func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		_ = ws.WriteMessage(websocket.TextMessage, []byte("ok:"))
+	})
+	return mux
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	Openapi "jcli/client"
+	"net/http"
+)
+
+const request_id_header = "X-Request-Id"
+
+// The engine does not send a message together with 304 Not Modified.
+var not_modified_messages = map[string]string{
+	"container start": "container already started",
+	"container stop":  "container already stopped",
+}
+
+// EngineError is returned when the jocker engine responds with an unexpected status code.
+type EngineError struct {
+	// The operation that failed, e.g. "container stop".
+	Operation  string
+	StatusCode int
+	// The message of the ErrorResponse sent by the engine, if any.
+	Message string
+	// The request ID of the response, if the engine (or a proxy in front of it) sets one.
+	RequestID string
+}
+
+func (e *EngineError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	details := fmt.Sprintf("status %d", e.StatusCode)
+	if e.RequestID != "" {
+		details += ", request ID " + e.RequestID
+	}
+	return fmt.Sprintf("%s failed: %s (%s)", e.Operation, message, details)
+}
+
+// NewEngineError creates an EngineError from one of the generated response types.
+func NewEngineError(operation string, response ResponseWithCode) *EngineError {
+	engine_error := &EngineError{Operation: operation, StatusCode: response.StatusCode()}
+
+	error_response, http_response := response_details(response)
+	if error_response != nil {
+		engine_error.Message = error_response.Message
+	}
+	if http_response != nil {
+		engine_error.RequestID = http_response.Header.Get(request_id_header)
+	}
+
+	if engine_error.Message == "" && engine_error.StatusCode == http.StatusNotModified {
+		engine_error.Message = not_modified_messages[operation]
+	}
+	return engine_error
+}
+
+// response_details returns the ErrorResponse sent by the engine, if any, and
+// the http response of one of the generated response types. New response
+// types must be added when the client is regenerated.
+func response_details(response ResponseWithCode) (*Openapi.ErrorResponse, *http.Response) {
+	switch r := response.(type) {
+	case *Openapi.ContainerCreateResponse:
+		return r.JSON500, r.HTTPResponse
+	case *Openapi.ContainerListResponse:
+		return nil, r.HTTPResponse
+	case *Openapi.ContainerDeleteResponse:
+		return first_error_response(r.JSON404, r.JSON500), r.HTTPResponse
+	case *Openapi.ContainerStartResponse:
+		return first_error_response(r.JSON304, r.JSON404, r.JSON500), r.HTTPResponse
+	case *Openapi.ContainerStopResponse:
+		return first_error_response(r.JSON304, r.JSON404, r.JSON500), r.HTTPResponse
+	case *Openapi.ImageListResponse:
+		return nil, r.HTTPResponse
+	case *Openapi.ImageRemoveResponse:
+		return r.JSON404, r.HTTPResponse
+	case *Openapi.NetworkCreateResponse:
+		return first_error_response(r.JSON409, r.JSON500), r.HTTPResponse
+	case *Openapi.NetworkListResponse:
+		return r.JSON500, r.HTTPResponse
+	case *Openapi.NetworkRemoveResponse:
+		return first_error_response(r.JSON404, r.JSON500), r.HTTPResponse
+	case *Openapi.NetworkConnectResponse:
+		return first_error_response(r.JSON404, r.JSON409, r.JSON500), r.HTTPResponse
+	case *Openapi.NetworkDisconnectResponse:
+		return first_error_response(r.JSON404, r.JSON500), r.HTTPResponse
+	case *Openapi.VolumeCreateResponse:
+		return r.JSON500, r.HTTPResponse
+	case *Openapi.VolumeListResponse:
+		return nil, r.HTTPResponse
+	case *Openapi.VolumeRemoveResponse:
+		return first_error_response(r.JSON404, r.JSON500), r.HTTPResponse
+	}
+	return nil, nil
+}
+
+// first_error_response returns the first ErrorResponse that is not nil. Only
+// the field of the status code of the response is set by the generated client.
+func first_error_response(error_responses ...*Openapi.ErrorResponse) *Openapi.ErrorResponse {
+	for _, error_response := range error_responses {
+		if error_response != nil {
+			return error_response
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestEngineErrors(t *testing.T) {
+	var container_id string
+	t.Run("create a container that sleeps when started", SuccesfullyCreateContainer(t, &container_id, "testerer", "base", []string{"/bin/sleep", "10"}))
+	t.Run("stopping a container that is already stopped", func(t *testing.T) {
+		_, err := ContainerStop(NewTestClient(t), container_id)
+		engine_error := ExpectEngineError(t, err, "container stop", http.StatusNotModified)
+		assert.Equal(t, engine_error.Message, "container already stopped")
+	})
+	t.Run("starting a container", StartContainer(container_id, false))
+	t.Run("starting a container that is already started", func(t *testing.T) {
+		_, err := StartSingleContainer(NewTestClient(t), container_id)
+		engine_error := ExpectEngineError(t, err, "container start", http.StatusNotModified)
+		assert.Equal(t, engine_error.Message, "container already started")
+	})
+	t.Run("removing a running container returns the message of the engine", func(t *testing.T) {
+		_, err := PostContainerRemove(NewTestClient(t), container_id)
+		engine_error := ExpectEngineError(t, err, "container remove", http.StatusInternalServerError)
+		assert.Assert(t, engine_error.Message != "")
+		assert.ErrorContains(t, err, engine_error.Message)
+		if fake_engine != nil {
+			assert.Assert(t, engine_error.RequestID != "")
+		}
+	})
+	t.Run("stop container", StopContainer(container_id))
+	t.Run("remove container", SuccesfullyRemoveContainer(t, container_id))
+	t.Run("removing an unknown container", func(t *testing.T) {
+		_, err := PostContainerRemove(NewTestClient(t), container_id)
+		ExpectEngineError(t, err, "container remove", http.StatusNotFound)
+	})
+	t.Run("removing an unknown network", func(t *testing.T) {
+		_, errs := RemoveNetworks(NewTestClient(t), []string{"nonexistingnetwork"})
+		ExpectEngineError(t, errs[0], "network remove", http.StatusNotFound)
+	})
+}
+
+func ExpectEngineError(t *testing.T, err error, operation string, status_code int) *EngineError {
+	var engine_error *EngineError
+	assert.Assert(t, errors.As(err, &engine_error))
+	assert.Equal(t, engine_error.Operation, operation)
+	assert.Equal(t, engine_error.StatusCode, status_code)
+	return engine_error
+}
+
+func TestEngineErrorMessage(t *testing.T) {
+	err := &EngineError{Operation: "network connect", StatusCode: 409, Message: "container already connected to the network", RequestID: "abc123"}
+	assert.Equal(t, err.Error(), "network connect failed: container already connected to the network (status 409, request ID abc123)")
+	err = &EngineError{Operation: "network list", StatusCode: 502}
+	assert.Equal(t, err.Error(), "network list failed: Bad Gateway (status 502)")
+}
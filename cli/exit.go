@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Exit codes of jcli. They follow the conventions of 'docker run', except that
+// the engine does not report the exit code of a container, so an attached
+// container that stops exits with 0.
+const (
+	// Generic errors, e.g. invalid arguments or configuration.
+	ExitGeneric = 1
+	// The engine returned an error or could not be reached.
+	ExitEngine = 125
+)
+
+// The reason of the close frame sent by the engine when an attached container
+// exits, e.g. "exit:container 4b8e2f9c1a3d stopped".
+var container_exit_pattern = regexp.MustCompile(`^container (\S+) stopped`)
+
+// StatusError makes jcli exit with Status. Err is nil when there is nothing to
+// report, e.g. when the errors have already been printed.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Status)
+	}
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of jcli for an error returned by Execute.
+func ExitCode(err error) int {
+	var status_error *StatusError
+	var engine_error *EngineError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &status_error):
+		return status_error.Status
+	case errors.As(err, &engine_error):
+		return ExitEngine
+	}
+	return ExitGeneric
+}
+
+// ContainerExit is the result of an attached container, as reported by the
+// engine when the container exits.
+type ContainerExit struct {
+	ContainerID string
+	// Message is the exit message of the engine, e.g. "container 4b8e2f9c1a3d stopped".
+	Message string
+}
+
+// ParseContainerExit parses the exit message of the engine, i.e. the reason of
+// the close frame following "exit:".
+func ParseContainerExit(message string) (*ContainerExit, error) {
+	match := container_exit_pattern.FindStringSubmatch(message)
+	if match == nil {
+		return nil, fmt.Errorf("unrecognized exit message from the engine: %q", message)
+	}
+	return &ContainerExit{ContainerID: match[1], Message: message}, nil
+}
+
+// AttachedContainerExit parses the exit message of an attached container. The
+// container has stopped even if the message is not recognized, so a warning is
+// printed instead of returning an error.
+func AttachedContainerExit(message string) *ContainerExit {
+	exit, err := ParseContainerExit(message)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+		return &ContainerExit{Message: message}
+	}
+	return exit
+}
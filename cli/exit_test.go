@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	Openapi "jcli/client"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExitCodes(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+
+	t.Run("success exits with 0", ExpectExitCode(0, "network", "list"))
+	t.Run("invalid arguments are generic errors", ExpectExitCode(ExitGeneric, "container", "create"))
+	t.Run("unknown commands are generic errors", ExpectExitCode(ExitGeneric, "nonexisting"))
+	t.Run("unimplemented commands are generic errors", ExpectExitCode(ExitGeneric, "image", "build"))
+	t.Run("group commands print their help", func(t *testing.T) {
+		for _, group := range []string{"container", "image", "network", "volume"} {
+			stdout, err := ExecuteRootCommand(group)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(stdout, "Available Commands:"), stdout)
+		}
+	})
+	t.Run("engine errors exit with 125", ExpectExitCode(ExitEngine, "container", "rm", "nonexisting"))
+	t.Run("an unreachable engine exits with 125", WithHost("tcp://127.0.0.1:1", func(t *testing.T) {
+		driver := "loopback"
+		_, err := NetworkCreate([]string{"testnet"}, Openapi.NetworkCreateJSONRequestBody{Driver: &driver})
+		assert.Equal(t, ExitCode(err), ExitEngine)
+	}))
+	t.Run("errors without an exit code are generic errors", func(t *testing.T) {
+		assert.Equal(t, ExitCode(errors.New("something failed")), ExitGeneric)
+	})
+	t.Run("attached containers stopped by the engine exit with 0", ExpectContainerExitCode("/usr/bin/true", 0))
+}
+
+func TestParseContainerExit(t *testing.T) {
+	t.Run("stopped", ExpectContainerExit("container 4b8e2f9c1a3d stopped", ContainerExit{ContainerID: "4b8e2f9c1a3d"}))
+	t.Run("unrecognized messages", func(t *testing.T) {
+		_, err := ParseContainerExit("jail removed")
+		assert.ErrorContains(t, err, `unrecognized exit message from the engine: "jail removed"`)
+	})
+	t.Run("attached containers with an unrecognized exit message have stopped", func(t *testing.T) {
+		exit := AttachedContainerExit("jail removed")
+		assert.DeepEqual(t, *exit, ContainerExit{Message: "jail removed"})
+	})
+	t.Run("the exit of an attached container is returned", func(t *testing.T) {
+		var container_id string
+		t.Run("create container", SuccesfullyCreateContainer(t, &container_id, "exitcode", "base", []string{"/usr/bin/true"}))
+		var exit *ContainerExit
+		var err error
+		RunCommandCollectStdOut(func() { exit, err = StartAttached(container_id, AttachOptions{}) })
+		assert.NilError(t, err)
+		assert.DeepEqual(t, *exit, ContainerExit{ContainerID: container_id, Message: "container " + container_id + " stopped"})
+		t.Run("remove container", SuccesfullyRemoveContainer(t, container_id))
+	})
+}
+
+func ExpectContainerExit(message string, expected ContainerExit) func(*testing.T) {
+	return func(t *testing.T) {
+		expected.Message = message
+		exit, err := ParseContainerExit(message)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, *exit, expected)
+	}
+}
+
+func ExpectExitCode(exit_code int, args ...string) func(*testing.T) {
+	return func(t *testing.T) {
+		_, err := ExecuteRootCommand(args...)
+		assert.Equal(t, ExitCode(err), exit_code)
+	}
+}
+
+func ExpectContainerExitCode(command string, exit_code int) func(*testing.T) {
+	return func(t *testing.T) {
+		var container_id string
+		t.Run("create container", SuccesfullyCreateContainer(t, &container_id, "exitcode", "base", []string{command}))
+		var err error
+		RunCommandCollectStdOut(func() { err = StartAndAttachToContainer([]string{container_id}, AttachOptions{}) })
+		assert.Equal(t, ExitCode(err), exit_code)
+		t.Run("remove container", SuccesfullyRemoveContainer(t, container_id))
+	}
+}
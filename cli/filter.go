@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	Openapi "jcli/client"
+)
+
+const (
+	filter_name    = "name"
+	filter_image   = "image"
+	filter_status  = "status"
+	filter_since   = "since"
+	filter_before  = "before"
+	filter_network = "network"
+)
+
+// ContainerFilter selects containers from the container list. The list is
+// filtered client-side, since the engine only supports listing all or the
+// running containers. Expressions with the same key are OR'ed together, and
+// expressions with different keys are AND'ed.
+type ContainerFilter struct {
+	names    []func(string) bool
+	images   []func(string) bool
+	statuses []bool
+	since    []time.Time
+	before   []time.Time
+}
+
+// ParseContainerFilter parses --filter expressions of the form 'key=value'.
+func ParseContainerFilter(expressions []string) (*ContainerFilter, error) {
+	filter := &ContainerFilter{}
+	now := time.Now()
+
+	for _, expression := range expressions {
+		key, value, found := cut(expression, "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("invalid filter '%s', expected 'key=value'", expression)
+		}
+
+		key = strings.ToLower(key)
+		switch key {
+		case filter_name:
+			match, err := pattern_matcher(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter '%s': %w", expression, err)
+			}
+			filter.names = append(filter.names, match)
+
+		case filter_image:
+			match, err := pattern_matcher(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter '%s': %w", expression, err)
+			}
+			filter.images = append(filter.images, match)
+
+		case filter_status:
+			switch strings.ToLower(value) {
+			case "running":
+				filter.statuses = append(filter.statuses, true)
+			case "stopped":
+				filter.statuses = append(filter.statuses, false)
+			default:
+				return nil, fmt.Errorf("invalid filter '%s': status must be 'running' or 'stopped'", expression)
+			}
+
+		case filter_since, filter_before:
+			timestamp, err := parse_filter_time(value, now)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter '%s': %w", expression, err)
+			}
+			if key == filter_since {
+				filter.since = append(filter.since, timestamp)
+			} else {
+				filter.before = append(filter.before, timestamp)
+			}
+
+		case filter_network:
+			return nil, fmt.Errorf("invalid filter '%s': the engine does not report the networks of containers in the container list", expression)
+
+		default:
+			return nil, fmt.Errorf(
+				"unknown filter '%s', expected one of %s",
+				key, strings.Join([]string{filter_name, filter_image, filter_status, filter_since, filter_before}, ", "),
+			)
+		}
+	}
+	return filter, nil
+}
+
+// IncludesStopped returns true if the filter can match stopped containers,
+// i.e., if all containers should be requested from the engine.
+func (f *ContainerFilter) IncludesStopped() bool {
+	for _, running := range f.statuses {
+		if !running {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if the container matches all of the filter keys.
+func (f *ContainerFilter) Match(container Openapi.ContainerSummary) bool {
+	created := created_time(container.Created)
+
+	if len(f.names) > 0 && !match_any(f.names, deref(container.Name)) {
+		return false
+	}
+	if len(f.images) > 0 && !match_image(f.images, container) {
+		return false
+	}
+	if len(f.statuses) > 0 {
+		running := container.Running != nil && *container.Running
+		matched := false
+		for _, status := range f.statuses {
+			matched = matched || status == running
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, since := range f.since {
+		if !created.After(since) {
+			return false
+		}
+	}
+	for _, before := range f.before {
+		if !created.Before(before) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the containers matching the filter.
+func (f *ContainerFilter) Apply(containers []Openapi.ContainerSummary) []Openapi.ContainerSummary {
+	filtered := []Openapi.ContainerSummary{}
+	for _, container := range containers {
+		if f.Match(container) {
+			filtered = append(filtered, container)
+		}
+	}
+	return filtered
+}
+
+// LastCreated returns the n most recently created containers, newest first.
+func LastCreated(containers []Openapi.ContainerSummary, n int) []Openapi.ContainerSummary {
+	sorted := append([]Openapi.ContainerSummary{}, containers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return created_time(sorted[i].Created).After(created_time(sorted[j].Created))
+	})
+	if n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// pattern_matcher returns a matcher for a glob pattern, or a regular expression
+// if the pattern is enclosed in slashes, e.g. '/^ci-[0-9]+$/'.
+func pattern_matcher(pattern string) (func(string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob pattern: %w", err)
+	}
+	return func(s string) bool {
+		matched, _ := path.Match(pattern, s)
+		return matched
+	}, nil
+}
+
+func match_any(matchers []func(string) bool, s string) bool {
+	for _, match := range matchers {
+		if match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// match_image matches the image name, 'name:tag' and the image id.
+func match_image(matchers []func(string) bool, container Openapi.ContainerSummary) bool {
+	name := deref(container.ImageName)
+	candidates := []string{name, deref(container.ImageId)}
+	if tag := deref(container.ImageTag); tag != "" {
+		candidates = append(candidates, name+":"+tag)
+	}
+	for _, candidate := range candidates {
+		if candidate != "" && match_any(matchers, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse_filter_time parses a timestamp, or a duration relative to now as
+// printed in the CREATED column, e.g. '2 hours ago'.
+func parse_filter_time(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if timestamp, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return timestamp, nil
+		}
+	}
+	duration, err := ParseHumanDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a duration or a timestamp: %w", err)
+	}
+	return now.Add(-duration), nil
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	Openapi "jcli/client"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseHumanDuration(t *testing.T) {
+	t.Run("go durations", ExpectHumanDuration("90m", 90*time.Minute))
+	t.Run("days", ExpectHumanDuration("3d", 72*time.Hour))
+	t.Run("less than a second", ExpectHumanDuration("Less than a second", 0))
+	t.Run("about a minute", ExpectHumanDuration("About a minute ago", time.Minute))
+	t.Run("about an hour", ExpectHumanDuration("About an hour", time.Hour))
+	t.Run("hours ago", ExpectHumanDuration("2 hours ago", 2*time.Hour))
+	t.Run("a single unit", ExpectHumanDuration("1 week", 7*24*time.Hour))
+	t.Run("the output of HumanDuration is parsed", func(t *testing.T) {
+		for _, d := range []time.Duration{5 * time.Second, 3 * time.Minute, 5 * time.Hour, 4 * 24 * time.Hour, 3 * 7 * 24 * time.Hour} {
+			parsed, err := ParseHumanDuration(HumanDuration(d))
+			assert.NilError(t, err)
+			assert.Equal(t, parsed, d)
+		}
+	})
+	t.Run("unknown units are rejected", func(t *testing.T) {
+		_, err := ParseHumanDuration("2 fortnights")
+		assert.ErrorContains(t, err, "unknown unit 'fortnight'")
+	})
+}
+
+func ExpectHumanDuration(s string, expected time.Duration) func(*testing.T) {
+	return func(t *testing.T) {
+		d, err := ParseHumanDuration(s)
+		assert.NilError(t, err)
+		assert.Equal(t, d, expected)
+	}
+}
+
+func TestContainerFilter(t *testing.T) {
+	now := time.Now()
+	containers := []Openapi.ContainerSummary{
+		NewContainerSummary("ci-1021", "builder", "latest", true, now.Add(-10*time.Minute)),
+		NewContainerSummary("ci-1020", "builder", "1.2", false, now.Add(-3*time.Hour)),
+		NewContainerSummary("webserver", "nginx", "latest", true, now.Add(-3*24*time.Hour)),
+	}
+
+	t.Run("no filters", ExpectFilteredContainers(containers, nil, "ci-1021", "ci-1020", "webserver"))
+	t.Run("name glob", ExpectFilteredContainers(containers, []string{"name=ci-*"}, "ci-1021", "ci-1020"))
+	t.Run("name regex", ExpectFilteredContainers(containers, []string{"name=/^ci-[0-9]+1$/"}, "ci-1021"))
+	t.Run("the same key is OR'ed", ExpectFilteredContainers(containers, []string{"name=ci-1020", "name=web*"}, "ci-1020", "webserver"))
+	t.Run("different keys are AND'ed", ExpectFilteredContainers(containers, []string{"name=ci-*", "status=running"}, "ci-1021"))
+	t.Run("image name", ExpectFilteredContainers(containers, []string{"image=nginx"}, "webserver"))
+	t.Run("image name and tag", ExpectFilteredContainers(containers, []string{"image=builder:1.*"}, "ci-1020"))
+	t.Run("status", ExpectFilteredContainers(containers, []string{"status=stopped"}, "ci-1020"))
+	t.Run("since a duration", ExpectFilteredContainers(containers, []string{"since=1 hour"}, "ci-1021"))
+	t.Run("before a duration", ExpectFilteredContainers(containers, []string{"before=About an hour ago"}, "ci-1020", "webserver"))
+	t.Run("since a timestamp", ExpectFilteredContainers(containers, []string{"since=" + now.Add(-4*time.Hour).Format(time.RFC3339)}, "ci-1021", "ci-1020"))
+	t.Run("the last created containers", func(t *testing.T) {
+		last := LastCreated([]Openapi.ContainerSummary{containers[2], containers[0], containers[1]}, 2)
+		assert.Equal(t, len(last), 2)
+		assert.Equal(t, *last[0].Name, "ci-1021")
+		assert.Equal(t, *last[1].Name, "ci-1020")
+	})
+
+	t.Run("invalid expressions", ExpectFilterError("name", "expected 'key=value'"))
+	t.Run("unknown keys", ExpectFilterError("size=10", "unknown filter 'size'"))
+	t.Run("invalid status", ExpectFilterError("status=paused", "status must be 'running' or 'stopped'"))
+	t.Run("invalid regex", ExpectFilterError("name=/ci-(/", "invalid filter 'name=/ci-(/'"))
+	t.Run("invalid durations", ExpectFilterError("since=yesterday", "expected a duration or a timestamp"))
+	t.Run("networks are not part of the container list", ExpectFilterError("network=default", "does not report the networks"))
+}
+
+func TestContainerListFilters(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+	var stopped_id, running_id string
+
+	t.Run("create a container", SuccesfullyCreateContainer(t, &stopped_id, "ci-stopped", "base", []string{"/bin/ls"}))
+	t.Run("create another container", SuccesfullyCreateContainer(t, &running_id, "ci-running", "base", []string{"/bin/sleep", "10"}))
+	t.Run("start it", StartContainer(running_id, false))
+	t.Run("only running containers are listed by default", ExpectOutput("ci-running\n", "--format", "{{.Name}}", "container", "ls", "--filter", "name=ci-*"))
+	t.Run("filtering on stopped containers lists all", ExpectOutput("ci-stopped\n", "--format", "{{.Name}}", "container", "ls", "--filter", "status=stopped"))
+	t.Run("filters can be repeated", ExpectOutput("ci-running\nci-stopped\n", "--format", "{{.Name}}", "container", "ls", "-a", "--filter", "name=ci-running", "--filter", "name=ci-stopped"))
+	t.Run("--latest shows the latest created container", ExpectOutput("ci-running\n", "--format", "{{.Name}}", "container", "ls", "--latest"))
+	t.Run("--last includes stopped containers", ExpectOutput("ci-running\nci-stopped\n", "--format", "{{.Name}}", "container", "ls", "--last", "2"))
+	t.Run("invalid filters are rejected before contacting the engine", func(t *testing.T) {
+		_, err := ExecuteRootCommand("container", "ls", "--filter", "status=paused")
+		assert.ErrorContains(t, err, "invalid filter 'status=paused'")
+	})
+	t.Run("stop container", StopContainer(running_id))
+	t.Run("remove container", SuccesfullyRemoveContainer(t, running_id))
+	t.Run("remove other container", SuccesfullyRemoveContainer(t, stopped_id))
+}
+
+func ExpectFilteredContainers(containers []Openapi.ContainerSummary, expressions []string, names ...string) func(*testing.T) {
+	return func(t *testing.T) {
+		filter, err := ParseContainerFilter(expressions)
+		assert.NilError(t, err)
+		filtered := []string{}
+		for _, container := range filter.Apply(containers) {
+			filtered = append(filtered, *container.Name)
+		}
+		assert.DeepEqual(t, filtered, names)
+	}
+}
+
+func ExpectFilterError(expression string, message string) func(*testing.T) {
+	return func(t *testing.T) {
+		_, err := ParseContainerFilter([]string{expression})
+		assert.ErrorContains(t, err, message)
+	}
+}
+
+func NewContainerSummary(name, image, tag string, running bool, created time.Time) Openapi.ContainerSummary {
+	id := name + "-id"
+	image_id := image + "-id"
+	timestamp := created.Format(time.RFC3339)
+	return Openapi.ContainerSummary{
+		Id:        &id,
+		Name:      &name,
+		ImageId:   &image_id,
+		ImageName: &image,
+		ImageTag:  &tag,
+		Running:   &running,
+		Created:   &timestamp,
+	}
+}
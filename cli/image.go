@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	Openapi "jcli/client"
 	"net/url"
@@ -8,7 +9,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var image_build_base_url = "ws://localhost:8085/images/build"
+const ws_image_build = "/images/build"
 
 func ImageCommand() *cobra.Command {
 	containerCmd := &cobra.Command{
@@ -16,8 +17,8 @@ func ImageCommand() *cobra.Command {
 		Short:                 "Manage images",
 		Long:                  `Manage images`,
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("The image main command have been executed")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
 		},
 	}
 
@@ -42,8 +43,8 @@ func ImageBuildCommand() *cobra.Command {
 		Long:                  `Build an image from a Dockerfile`,
 		Args:                  cobra.NoArgs,
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("The image build command have been executed")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errors.New("image build is not implemented yet")
 		},
 	}
 	flags := cmd.Flags()
@@ -60,41 +61,108 @@ func ImageRemoveCommand() *cobra.Command {
 		Long:                  `Remove one or more images`,
 		Args:                  cobra.MinimumNArgs(1),
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("The image rm command have been executed")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := NewHTTPClient()
+			if err != nil {
+				return err
+			}
+			responses, errs := RemoveImages(client, args)
+			return print_results(errs, func(idx int) Openapi.IdResponse { return *responses[idx].JSON200 })
 		},
 	}
 	return cmd
 }
 
+func RemoveImages(client *Openapi.ClientWithResponses, name_or_ids []string) ([]*Openapi.ImageRemoveResponse, []error) {
+	errs := make([]error, len(name_or_ids))
+	responses := make([]*Openapi.ImageRemoveResponse, len(name_or_ids))
+	for idx, name_or_id := range name_or_ids {
+		ctx, cancel := RequestContext()
+		response, err := client.ImageRemoveWithResponse(ctx, name_or_id)
+		cancel()
+		errs[idx] = verify_response("image remove", response, 200, err)
+		responses[idx] = response
+	}
+	return responses, errs
+}
+
 func ImageListCommand() *cobra.Command {
+	var quiet bool
+
 	cmd := &cobra.Command{
 		Use:                   "list",
 		Short:                 "List images",
 		Long:                  `List images`,
 		Args:                  cobra.NoArgs,
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("The image ls command have been executed")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			response, err := ImageList()
+			if err != nil {
+				return err
+			}
+			if quiet {
+				ids := make([]string, len(*response.JSON200))
+				for i, image := range *response.JSON200 {
+					ids[i] = *image.Id
+				}
+				return PrintQuiet(ids)
+			}
+			return PrintOutput(response.JSON200, func() error { return PrintImageList(response.JSON200) })
 		},
 	}
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only display image IDs")
 	return cmd
 }
 
-func BuildImageAndListenForMessages(options ImageBuildOptions) {
-	ws_url, _ := url.Parse(image_build_base_url)
-	query := ws_url.Query()
+func ImageList() (*Openapi.ImageListResponse, error) {
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := RequestContext()
+	defer cancel()
+
+	response, err := client.ImageListWithResponse(ctx)
+	err = verify_response("image list", response, 200, err)
+	return response, err
+}
+
+func PrintImageList(images *[]Openapi.Image) error {
+	table := Table{Columns: []Column{
+		{Name: "id", Header: "IMAGE ID"},
+		{Name: "name", Header: "NAME"},
+		{Name: "tag", Header: "TAG"},
+		{Name: "created", Header: "CREATED"},
+	}}
+	for _, image := range *images {
+		table.AddRow(Row{
+			Cells:   map[string]string{"id": *image.Id, "name": *image.Name, "tag": *image.Tag, "created": created_ago(image.Created)},
+			Name:    *image.Name,
+			Created: created_time(image.Created),
+		})
+	}
+	return table.Print()
+}
+
+func BuildImageAndListenForMessages(options ImageBuildOptions) error {
+	query := url.Values{}
 	query.Set("context", options.Context)
 	query.Set("dockerfile", options.Dockerfile)
 	query.Set("tag", options.Tag)
 	query.Set("quiet", fmt.Sprint(options.Quiet))
-	ws_url.RawQuery = query.Encode()
-	endpoint := ws_url.String()
 
-	done, interrupt, ws := Dial(endpoint)
-	go ListenForWSMessages(done, ws)
-	BuildImage(NewHTTPClient())
-	AwaitDoneOrUserInterrupt(done, interrupt, ws)
+	done, interrupt, ws, err := Dial(ws_image_build, query)
+	if err != nil {
+		return err
+	}
+	client, err := NewHTTPClient()
+	if err != nil {
+		ws.Close()
+		return err
+	}
+	go ListenForWSMessages(done, ws, nil)
+	BuildImage(client)
+	return AwaitDoneOrUserInterrupt(done, interrupt, ws, nil)
 }
 
 func BuildImage(client *Openapi.ClientWithResponses) {
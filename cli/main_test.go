@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	Openapi "jcli/client"
+	"jcli/fakeengine"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The tests run against an in-memory fake engine unless JCLI_TEST_HOST points
+// to a real engine, e.g. JCLI_TEST_HOST=tcp://localhost:8085 on a FreeBSD host.
+var fake_engine *fakeengine.Engine
+
+func TestMain(m *testing.M) {
+	if test_host := os.Getenv("JCLI_TEST_HOST"); test_host != "" {
+		host = test_host
+		os.Exit(m.Run())
+	}
+
+	engine, server := fakeengine.NewServer()
+	fake_engine = engine
+	host = server.URL
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
+// ClearHost unsets --host for the duration of the test, for tests that
+// select the engine through contexts.
+func ClearHost(t *testing.T) {
+	old_host := host
+	host = ""
+	t.Cleanup(func() { host = old_host })
+}
+
+// NewTestClient returns a client for the engine of the tests.
+func NewTestClient(t *testing.T) *Openapi.ClientWithResponses {
+	client, err := NewHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// ExecuteRootCommand runs jcli with args and returns what it printed on stdout.
+func ExecuteRootCommand(args ...string) (string, error) {
+	// Execute replaces root_ctx with a context that is cancelled when it returns.
+	old_root_ctx := root_ctx
+	defer func() { root_ctx = old_root_ctx }()
+	defer ResetFlags(RootCmd)
+	RootCmd.SetArgs(args)
+	defer RootCmd.SetArgs(nil)
+
+	var err error
+	stdout := RunCommandCollectStdOut(func() { err = Execute() })
+	return stdout, err
+}
+
+// ResetFlags restores the default values of the flags changed by executing cmd.
+func ResetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if slice, is_slice := flag.Value.(pflag.SliceValue); is_slice {
+			values := []string{}
+			if defaults := strings.Trim(flag.DefValue, "[]"); defaults != "" {
+				values = strings.Split(defaults, ",")
+			}
+			_ = slice.Replace(values)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.Flags().VisitAll(reset)
+	for _, subcommand := range cmd.Commands() {
+		ResetFlags(subcommand)
+	}
+}
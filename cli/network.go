@@ -1,8 +1,6 @@
 package cli
 
 import (
-	"context"
-	"fmt"
 	Openapi "jcli/client"
 
 	"github.com/spf13/cobra"
@@ -14,8 +12,8 @@ func NetworkCommand() *cobra.Command {
 		Short:                 "Manage networks",
 		Long:                  `Manage networks`,
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("The network main command have been executed")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
 		},
 	}
 
@@ -28,34 +26,58 @@ func NetworkCommand() *cobra.Command {
 }
 
 func NetworkListCommand() *cobra.Command {
+	var quiet bool
+
 	cmd := &cobra.Command{
 		Use:                   "list",
 		Short:                 "List networks",
 		Long:                  `List networks`,
 		DisableFlagsInUseLine: true,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			response, err := NetworkList()
-			if err == nil {
-				PrintNetworkList(response.JSON200)
+			if err != nil {
+				return err
+			}
+			if quiet {
+				ids := make([]string, len(*response.JSON200))
+				for i, network := range *response.JSON200 {
+					ids[i] = *network.Id
+				}
+				return PrintQuiet(ids)
 			}
+			return PrintOutput(response.JSON200, func() error { return PrintNetworkList(response.JSON200) })
 		},
 	}
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only display network IDs")
 
 	return cmd
 }
 func NetworkList() (*Openapi.NetworkListResponse, error) {
-	client := NewHTTPClient()
-	response, err := client.NetworkListWithResponse(context.TODO())
-	err = verify_response(response, 200, err)
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := RequestContext()
+	defer cancel()
+
+	response, err := client.NetworkListWithResponse(ctx)
+	err = verify_response("network list", response, 200, err)
 	return response, err
 }
 
-func PrintNetworkList(networks *[]Openapi.NetworkSummary) {
-	fmt.Println(Cell("NETWORK ID", 12), Cell("NAME", 25), "DRIVER")
+func PrintNetworkList(networks *[]Openapi.NetworkSummary) error {
+	table := Table{Columns: []Column{
+		{Name: "id", Header: "NETWORK ID"},
+		{Name: "name", Header: "NAME"},
+		{Name: "driver", Header: "DRIVER"},
+	}}
 	for _, network := range *networks {
-		fmt.Println(Cell(*network.Id, 12), Cell(*network.Name, 25), *network.Driver)
-
+		table.AddRow(Row{
+			Cells: map[string]string{"id": *network.Id, "name": *network.Name, "driver": *network.Driver},
+			Name:  *network.Name,
+		})
 	}
+	return table.Print()
 }
 
 func NetworkCreateCommand() *cobra.Command {
@@ -73,7 +95,13 @@ func NetworkCreateCommand() *cobra.Command {
 		Long:                  `Create a new network`,
 		Args:                  cobra.MinimumNArgs(1),
 		DisableFlagsInUseLine: true,
-		Run:                   func(cmd *cobra.Command, args []string) { NetworkCreate(args, config) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			response, err := NetworkCreate(args, config)
+			if err != nil {
+				return err
+			}
+			return PrintIdResponse(response.JSON201)
+		},
 	}
 
 	flags := cmd.Flags()
@@ -85,12 +113,15 @@ func NetworkCreateCommand() *cobra.Command {
 
 func NetworkCreate(args []string, config Openapi.NetworkCreateJSONRequestBody) (*Openapi.NetworkCreateResponse, error) {
 	config.Name = args[0]
-	client := NewHTTPClient()
-	response, err := client.NetworkCreateWithResponse(context.TODO(), config)
-	err = verify_response(response, 201, err)
-	if err == nil {
-		fmt.Println(response.JSON201.Id)
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
 	}
+	ctx, cancel := RequestContext()
+	defer cancel()
+
+	response, err := client.NetworkCreateWithResponse(ctx, config)
+	err = verify_response("network create", response, 201, err)
 	return response, err
 }
 
@@ -101,22 +132,26 @@ func NetworkRemoveCommand() *cobra.Command {
 		Long:                  `Remove a network`,
 		Args:                  cobra.MinimumNArgs(1),
 		DisableFlagsInUseLine: true,
-		Run:                   func(cmd *cobra.Command, args []string) { RemoveNetworks(args) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := NewHTTPClient()
+			if err != nil {
+				return err
+			}
+			responses, errs := RemoveNetworks(client, args)
+			return print_results(errs, func(idx int) Openapi.IdResponse { return *responses[idx].JSON200 })
+		},
 	}
 	return cmd
 }
 
-func RemoveNetworks(name_or_ids []string) ([]*Openapi.NetworkRemoveResponse, []error) {
+func RemoveNetworks(client *Openapi.ClientWithResponses, name_or_ids []string) ([]*Openapi.NetworkRemoveResponse, []error) {
 	errs := make([]error, len(name_or_ids))
 	responses := make([]*Openapi.NetworkRemoveResponse, len(name_or_ids))
 	for idx, name_or_id := range name_or_ids {
-		client := NewHTTPClient()
-		response, err := client.NetworkRemoveWithResponse(context.TODO(), name_or_id)
-		err = verify_response(response, 200, err)
-		if err == nil {
-			fmt.Println(response.JSON200.Id)
-		}
-		errs[idx] = err
+		ctx, cancel := RequestContext()
+		response, err := client.NetworkRemoveWithResponse(ctx, name_or_id)
+		cancel()
+		errs[idx] = verify_response("network remove", response, 200, err)
 		responses[idx] = response
 	}
 	return responses, errs
@@ -129,7 +164,10 @@ func NetworkConnectCommand() *cobra.Command {
 		Long:                  `Connect a container to a network`,
 		Args:                  cobra.ExactArgs(2),
 		DisableFlagsInUseLine: true,
-		Run:                   func(cmd *cobra.Command, args []string) { NetworkConnect(args) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := NetworkConnect(args)
+			return err
+		},
 	}
 	return cmd
 }
@@ -137,9 +175,15 @@ func NetworkConnectCommand() *cobra.Command {
 func NetworkConnect(args []string) (*Openapi.NetworkConnectResponse, error) {
 	network_name := args[0]
 	container_name := args[1]
-	client := NewHTTPClient()
-	response, err := client.NetworkConnectWithResponse(context.TODO(), network_name, container_name)
-	err = verify_response(response, 204, err)
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := RequestContext()
+	defer cancel()
+
+	response, err := client.NetworkConnectWithResponse(ctx, network_name, container_name)
+	err = verify_response("network connect", response, 204, err)
 	return response, err
 }
 
@@ -150,7 +194,10 @@ func NetworkDisconnectCommand() *cobra.Command {
 		Long:                  `Disconnect a container from a network`,
 		Args:                  cobra.ExactArgs(2),
 		DisableFlagsInUseLine: true,
-		Run:                   func(cmd *cobra.Command, args []string) { NetworkDisconnect(args) },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := NetworkDisconnect(args)
+			return err
+		},
 	}
 	return cmd
 }
@@ -158,8 +205,14 @@ func NetworkDisconnectCommand() *cobra.Command {
 func NetworkDisconnect(args []string) (*Openapi.NetworkDisconnectResponse, error) {
 	network_name := args[0]
 	container_name := args[1]
-	client := NewHTTPClient()
-	response, err := client.NetworkDisconnectWithResponse(context.TODO(), network_name, container_name)
-	err = verify_response(response, 204, err)
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := RequestContext()
+	defer cancel()
+
+	response, err := client.NetworkDisconnectWithResponse(ctx, network_name, container_name)
+	err = verify_response("network disconnect", response, 204, err)
 	return response, err
 }
@@ -121,7 +121,7 @@ type InterfaceInfo struct {
 
 func SuccesfullyRemoveANetwork(t *testing.T, network_id *string, name_or_id string) func(*testing.T) {
 	return func(t *testing.T) {
-		response, errs := RemoveNetworks([]string{name_or_id})
+		response, errs := RemoveNetworks(NewTestClient(t), []string{name_or_id})
 		assert.NilError(t, errs[0])
 		*network_id = response[0].JSON200.Id
 	}
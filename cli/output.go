@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	Openapi "jcli/client"
+	"os"
+	"reflect"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	format_table = "table"
+	format_json  = "json"
+	format_yaml  = "yaml"
+)
+
+// ValidateFormat returns an error if --format is neither a supported output
+// format nor a valid Go template.
+func ValidateFormat() error {
+	switch output_format {
+	case format_table, format_json, format_yaml:
+		return nil
+	}
+	if _, _, err := ParseFormatTemplate(output_format); err != nil {
+		return fmt.Errorf("invalid format, expected '%s', '%s', '%s' or a Go template: %w", format_table, format_json, format_yaml, err)
+	}
+	return nil
+}
+
+// PrintOutput prints value as JSON or YAML, using the field names of the engine
+// API, or with the Go template given with --format. It calls print_table
+// when the table format is selected.
+func PrintOutput(value interface{}, print_table func() error) error {
+	var output []byte
+	var err error
+	switch output_format {
+	case format_json:
+		output, err = json.MarshalIndent(value, "", "  ")
+		output = append(output, '\n')
+	case format_yaml:
+		output, err = yaml.Marshal(value)
+	case format_table:
+		return print_table()
+	default:
+		return print_template(value)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(output)
+	return err
+}
+
+// print_template executes the template given with --format for value, or for
+// each of its items if value is a list.
+func print_template(value interface{}) error {
+	tmpl, is_table, err := ParseFormatTemplate(output_format)
+	if err != nil {
+		return err
+	}
+
+	var output io.Writer = os.Stdout
+	if is_table {
+		table := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+		defer table.Flush()
+		fmt.Fprintln(table, TemplateHeader(output_format))
+		output = table
+	}
+
+	items := []interface{}{value}
+	if list := reflect.Indirect(reflect.ValueOf(value)); list.Kind() == reflect.Slice {
+		items = make([]interface{}, list.Len())
+		for i := range items {
+			items[i] = list.Index(i).Interface()
+		}
+	}
+	for _, item := range items {
+		if err := tmpl.Execute(output, item); err != nil {
+			return err
+		}
+		fmt.Fprintln(output)
+	}
+	return nil
+}
+
+// PrintIdResponse prints the response of a command that creates or modifies a single object.
+func PrintIdResponse(response *Openapi.IdResponse) error {
+	return PrintOutput(response, func() error {
+		fmt.Println(response.Id)
+		return nil
+	})
+}
+
+// PrintIdResponses prints the responses of a command that acts on several objects.
+func PrintIdResponses(responses []Openapi.IdResponse) error {
+	return PrintOutput(responses, func() error {
+		for _, response := range responses {
+			fmt.Println(response.Id)
+		}
+		return nil
+	})
+}
+
+// PrintQuiet prints only the IDs or names of the listed objects, one per line,
+// for passing them on to other commands. --format is ignored.
+func PrintQuiet(ids []string) error {
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+	return nil
+}
+
+// print_results prints the IDs of the objects that a command acting on several
+// objects succeeded on, followed by the errors of the others. id_response
+// returns the response for the object at idx, and is only called if errs[idx]
+// is nil.
+func print_results(errs []error, id_response func(idx int) Openapi.IdResponse) error {
+	succeeded := []Openapi.IdResponse{}
+	for idx, err := range errs {
+		if err == nil {
+			succeeded = append(succeeded, id_response(idx))
+		}
+	}
+	if err := PrintIdResponses(succeeded); err != nil {
+		return err
+	}
+	return report_errors(errs)
+}
+
+// report_errors prints the errors of a command that acts on several objects.
+// If any failed, the last error is returned as a *StatusError without a
+// message, so jcli exits with the corresponding exit code.
+func report_errors(errs []error) error {
+	var last_err error
+	for _, err := range errs {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			last_err = err
+		}
+	}
+	if last_err != nil {
+		return &StatusError{Status: ExitCode(last_err)}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	Openapi "jcli/client"
+
+	"github.com/ghodss/yaml"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestOutputFormats(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+	var container_id string
+
+	t.Run("create a container with JSON output", func(t *testing.T) {
+		var created Openapi.IdResponse
+		ExpectJSONOutput(t, &created, "--format", "json", "container", "create", "--name", "formatted", "base", "/bin/ls")
+		assert.Equal(t, len(created.Id), 12)
+		container_id = created.Id
+	})
+	t.Run("list containers as JSON", func(t *testing.T) {
+		var containers []Openapi.ContainerSummary
+		ExpectJSONOutput(t, &containers, "--format", "json", "container", "ls", "-a")
+		assert.Equal(t, len(containers), 1)
+		assert.Equal(t, *containers[0].Id, container_id)
+		assert.Equal(t, *containers[0].Name, "formatted")
+	})
+	t.Run("list containers as YAML with the field names of the API", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("--format", "yaml", "container", "ls", "-a")
+		assert.NilError(t, err)
+		assert.Assert(t, is.Contains(stdout, "image_id: "))
+		var containers []Openapi.ContainerSummary
+		assert.NilError(t, yaml.Unmarshal([]byte(stdout), &containers))
+		assert.Equal(t, *containers[0].Id, container_id)
+	})
+	t.Run("the table format prints bare IDs", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("container", "rm", container_id)
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, container_id+"\n")
+	})
+	t.Run("list networks as YAML", func(t *testing.T) {
+		var networks []Openapi.NetworkSummary
+		ExpectYAMLOutput(t, &networks, "--format", "yaml", "network", "list")
+		assert.Equal(t, *networks[0].Name, "default")
+	})
+	t.Run("list images as JSON", func(t *testing.T) {
+		var images []Openapi.Image
+		ExpectJSONOutput(t, &images, "--format", "json", "image", "list")
+		assert.Equal(t, *images[0].Name, "base")
+	})
+	t.Run("create a volume with JSON output", func(t *testing.T) {
+		var created Openapi.IdResponse
+		ExpectJSONOutput(t, &created, "--format", "json", "volume", "create", "testvol")
+		assert.Equal(t, created.Id, "testvol")
+	})
+	t.Run("list volumes as JSON", func(t *testing.T) {
+		var volumes []Openapi.VolumeSummary
+		ExpectJSONOutput(t, &volumes, "--format", "json", "volume", "ls")
+		assert.Equal(t, len(volumes), 1)
+		assert.Equal(t, *volumes[0].Name, "testvol")
+	})
+	t.Run("commands acting on several objects print a list", func(t *testing.T) {
+		var removed []Openapi.IdResponse
+		ExpectJSONOutput(t, &removed, "--format", "json", "volume", "rm", "testvol")
+		assert.DeepEqual(t, removed, []Openapi.IdResponse{{Id: "testvol"}})
+	})
+	t.Run("invalid formats are rejected before contacting the engine", func(t *testing.T) {
+		_, err := ExecuteRootCommand("--format", "{{.Id", "volume", "create", "testvol")
+		assert.ErrorContains(t, err, "invalid format")
+		response, err := VolumeList()
+		assert.NilError(t, err)
+		assert.Equal(t, len(*response.JSON200), 0)
+	})
+}
+
+func ExpectJSONOutput(t *testing.T, value interface{}, args ...string) {
+	stdout, err := ExecuteRootCommand(args...)
+	assert.NilError(t, err)
+	decoder := json.NewDecoder(strings.NewReader(stdout))
+	decoder.DisallowUnknownFields()
+	assert.NilError(t, decoder.Decode(value))
+}
+
+func ExpectYAMLOutput(t *testing.T, value interface{}, args ...string) {
+	stdout, err := ExecuteRootCommand(args...)
+	assert.NilError(t, err)
+	assert.NilError(t, yaml.Unmarshal([]byte(stdout), value))
+}
+
+const test_format_config = `
+container:
+  ls:
+    format: "{{.Name}} {{upper .ImageName}}"
+`
+
+func TestFormatTemplates(t *testing.T) {
+	config_home := t.TempDir()
+	assert.NilError(t, os.MkdirAll(filepath.Join(config_home, "jcli"), 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(config_home, "jcli", "config.yaml"), []byte(test_format_config), 0644))
+	SetEnvForTest(t, "XDG_CONFIG_HOME", config_home)
+	var container_id string
+
+	t.Run("create a container", SuccesfullyCreateContainer(t, &container_id, "templated", "base", []string{"/bin/ls"}))
+	t.Run("a template is executed for every object", ExpectOutput("templated "+container_id+"\n", "--format", "{{.Name}} {{.Id}}", "container", "ls", "-a"))
+	t.Run("the table directive prints aligned columns with headers", ExpectOutput(
+		"NAME      DRIVER\ndefault   loopback\nhost      host\n",
+		"--format", `table {{.Name}}\t{{.Driver}}`, "network", "list"))
+	t.Run("truncate", ExpectOutput(container_id[:4]+"\n", "--format", "{{truncate .Id 4}}", "container", "ls", "-a"))
+	t.Run("join", ExpectOutput("/bin/sh,/etc/rc\n", "--format", `{{join .Command ","}}`, "image", "list"))
+	t.Run("json", ExpectOutput(`"templated"`+"\n", "--format", "{{json .Name}}", "container", "ls", "-a"))
+	t.Run("humanDuration", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("--format", "{{humanDuration .Created}} ago", "container", "ls", "-a")
+		assert.NilError(t, err)
+		// The engine reports the creation time with a precision of seconds.
+		assert.Assert(t, is.Regexp(`^(Less than a second|1 second|[0-9]+ seconds) ago\n$`, stdout))
+		duration, err := template_human_duration(time.Now().Add(-3 * time.Hour).Format(time.RFC3339))
+		assert.NilError(t, err)
+		assert.Equal(t, duration, "3 hours")
+	})
+	t.Run("the template of a command can be set in the config file", ExpectOutput("templated BASE\n", "container", "ls", "-a"))
+	t.Run("--format takes precedence over the config file", ExpectOutput(container_id+"\n", "--format", "{{.Id}}", "container", "ls", "-a"))
+	t.Run("fields that do not exist are reported", func(t *testing.T) {
+		_, err := ExecuteRootCommand("--format", "{{.Size}}", "container", "ls", "-a")
+		assert.ErrorContains(t, err, "can't evaluate field Size")
+	})
+	t.Run("remove container", SuccesfullyRemoveContainer(t, container_id))
+}
+
+func TestTemplateHeader(t *testing.T) {
+	assert.Equal(t, TemplateHeader(`table {{.Id}}\t{{truncate .ImageId 6}}\t{{humanDuration .Created}} ago\tstatic`), "ID\tIMAGE ID\tCREATED\t")
+}
+
+func ExpectOutput(expected string, args ...string) func(*testing.T) {
+	return func(t *testing.T) {
+		stdout, err := ExecuteRootCommand(args...)
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, expected)
+	}
+}
+
+func TestQuietOutput(t *testing.T) {
+	SetEnvForTest(t, "XDG_CONFIG_HOME", t.TempDir())
+	var container_id string
+
+	t.Run("create a container", SuccesfullyCreateContainer(t, &container_id, "quiet", "base", []string{"/bin/ls"}))
+	t.Run("container IDs", ExpectOutput(container_id+"\n", "container", "ls", "-aq"))
+	t.Run("combined with filters", ExpectOutput(container_id+"\n", "container", "ls", "-q", "--filter", "status=stopped"))
+	t.Run("nothing is printed when no containers match", ExpectOutput("", "container", "ls", "-q"))
+	t.Run("--format is ignored", ExpectOutput(container_id+"\n", "--format", "json", "container", "ls", "-aq"))
+	t.Run("remove container", SuccesfullyRemoveContainer(t, container_id))
+	t.Run("network IDs", func(t *testing.T) {
+		response, err := NetworkList()
+		assert.NilError(t, err)
+		expected := ""
+		for _, network := range *response.JSON200 {
+			expected += *network.Id + "\n"
+		}
+		ExpectOutput(expected, "network", "list", "-q")(t)
+	})
+	t.Run("image IDs", func(t *testing.T) {
+		response, err := ImageList()
+		assert.NilError(t, err)
+		expected := ""
+		for _, image := range *response.JSON200 {
+			expected += *image.Id + "\n"
+		}
+		ExpectOutput(expected, "image", "list", "-q")(t)
+	})
+	t.Run("create a volume", func(t *testing.T) {
+		_, err := VolumeCreate("quietvol")
+		assert.NilError(t, err)
+	})
+	t.Run("volume names", ExpectOutput("quietvol\n", "volume", "ls", "-q"))
+	t.Run("remove volume", ExpectOutput("quietvol\n", "volume", "rm", "quietvol"))
+}
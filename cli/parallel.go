@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"sync"
+)
+
+// default_parallel is the default number of concurrent requests of commands
+// acting on several objects.
+const default_parallel = 4
+
+func validate_parallel(parallel int) error {
+	if parallel < 1 {
+		return fmt.Errorf("invalid value for --parallel: %d, expected a positive number", parallel)
+	}
+	return nil
+}
+
+// run_parallel calls f for every index in [0, n), with at most parallel calls
+// running at a time, and returns when all calls have returned.
+func run_parallel(n int, parallel int, f func(idx int)) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallel)
+	for idx := 0; idx < n; idx++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(idx int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			f(idx)
+		}(idx)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	Openapi "jcli/client"
+	"math/rand"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const retry_base_delay = 200 * time.Millisecond
+const retry_max_delay = 5 * time.Second
+
+// RetryDoer is a HttpRequestDoer that retries idempotent requests (GET, HEAD
+// and DELETE) when the engine is unavailable, e.g. while it restarts. Requests
+// are retried at most Retries times with jittered exponential backoff, and
+// never beyond the deadline of the request context. Other requests, such as
+// creating a container, are never retried since they are not safe to repeat.
+type RetryDoer struct {
+	Doer      Openapi.HttpRequestDoer
+	Retries   int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func (d *RetryDoer) Do(req *http.Request) (*http.Response, error) {
+	if !is_idempotent(req.Method) {
+		return d.Doer.Do(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		response, err := d.Doer.Do(req)
+		if attempt > d.Retries || !is_retryable(response, err) {
+			return response, err
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = response.Status
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+		delay := d.backoff(attempt)
+		debug_log("retrying %s %s in %s (retry %d of %d): %s", req.Method, req.URL, delay, attempt, d.Retries, reason)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a random delay between half and all of BaseDelay*2^(attempt-1), capped by MaxDelay.
+func (d *RetryDoer) backoff(attempt int) time.Duration {
+	delay := d.BaseDelay << (attempt - 1)
+	if delay > d.MaxDelay || delay <= 0 {
+		delay = d.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func is_idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete
+}
+
+// is_retryable reports whether the engine was unreachable. A missing unix
+// socket is treated like a refused connection since the engine removes it
+// when shutting down.
+func is_retryable(response *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT)
+	}
+	return response.StatusCode == http.StatusBadGateway || response.StatusCode == http.StatusServiceUnavailable
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRetryDoer(t *testing.T) {
+	var attempts, failures int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= atomic.LoadInt32(&failures) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	doer := &RetryDoer{Doer: http.DefaultClient, Retries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	reset := func(n int32) {
+		atomic.StoreInt32(&attempts, 0)
+		atomic.StoreInt32(&failures, n)
+	}
+
+	t.Run("GET is retried until the engine is available", func(t *testing.T) {
+		reset(2)
+		response, err := doer.Do(NewTestRequest(t, context.Background(), http.MethodGet, server.URL))
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.Equal(t, atomic.LoadInt32(&attempts), int32(3))
+	})
+
+	t.Run("DELETE is retried at most --retries times", func(t *testing.T) {
+		reset(10)
+		response, err := doer.Do(NewTestRequest(t, context.Background(), http.MethodDelete, server.URL))
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode, http.StatusServiceUnavailable)
+		assert.Equal(t, atomic.LoadInt32(&attempts), int32(4))
+	})
+
+	t.Run("POST is never retried", func(t *testing.T) {
+		reset(1)
+		response, err := doer.Do(NewTestRequest(t, context.Background(), http.MethodPost, server.URL))
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode, http.StatusServiceUnavailable)
+		assert.Equal(t, atomic.LoadInt32(&attempts), int32(1))
+	})
+
+	t.Run("retries stop when the request context is done", func(t *testing.T) {
+		reset(10)
+		slow_doer := &RetryDoer{Doer: http.DefaultClient, Retries: 10, BaseDelay: time.Second, MaxDelay: time.Second}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := slow_doer.Do(NewTestRequest(t, ctx, http.MethodGet, server.URL))
+		assert.Assert(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Equal(t, atomic.LoadInt32(&attempts), int32(1))
+	})
+
+	t.Run("refused connections are retried", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		address := listener.Addr().String()
+		listener.Close()
+
+		counting_doer := &CountingDoer{Doer: http.DefaultClient}
+		doer := &RetryDoer{Doer: counting_doer, Retries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+		_, err = doer.Do(NewTestRequest(t, context.Background(), http.MethodGet, "http://"+address))
+		assert.ErrorContains(t, err, "connection refused")
+		assert.Equal(t, counting_doer.requests, 3)
+	})
+}
+
+type CountingDoer struct {
+	Doer     *http.Client
+	requests int
+}
+
+func (d *CountingDoer) Do(req *http.Request) (*http.Response, error) {
+	d.requests++
+	return d.Doer.Do(req)
+}
+
+func NewTestRequest(t *testing.T, ctx context.Context, method, url string) *http.Request {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	assert.NilError(t, err)
+	return req
+}
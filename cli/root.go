@@ -4,31 +4,100 @@
 package cli
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Used for flags.
-	debug bool
-	host  string
+	debug        bool
+	host         string
+	context_name string
+	use_tls      bool
+	tls_verify   bool
+	tls_cacert   string
+	tls_cert     string
+	tls_key      string
+	// Output format of list and mutation commands: table, json, yaml or a Go template.
+	output_format string
+	no_trunc      bool
+	table_columns []string
+	table_sort    string
+
+	request_timeout time.Duration
+	retries         int
+	// Cancelled when jcli receives SIGINT or SIGTERM.
+	root_ctx = context.Background()
 
 	RootCmd = &cobra.Command{
 		Use:     "jcli",
 		Short:   "A cli-tool for jocker",
 		Long:    `JCli is the reference cli-tool for interacting with jocker-engine`,
 		Version: "0.0.1",
+		// Errors are printed by Execute, and are not usage errors in general.
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := LoadConfig(); err != nil {
+				return err
+			}
+			if err := ApplyConfig(cmd); err != nil {
+				return err
+			}
+			if err := ValidateFormat(); err != nil {
+				return err
+			}
+			return ValidateTableOptions()
+		},
 	}
 )
 
-// Execute executes the root command.
+// Execute executes the root command and prints the error, if any. Use
+// ExitCode to get the exit code for the error. Requests to the engine are
+// cancelled on SIGINT/SIGTERM, and a second signal terminates jcli immediately.
 func Execute() error {
-	return RootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	root_ctx = ctx
+	err := RootCmd.ExecuteContext(ctx)
+	var status_error *StatusError
+	if err != nil && !(errors.As(err, &status_error) && status_error.Err == nil) {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	return err
 }
 
 func init() {
 	RootCmd.PersistentFlags().BoolVarP(&debug, "debug", "D", false, "Enable debug mode")
+	RootCmd.PersistentFlags().StringVar(&config_file, "config", "", "Location of the client config file (default $XDG_CONFIG_HOME/jcli/config.yaml)")
 	RootCmd.PersistentFlags().StringVarP(&host, "host", "H", "", "Daemon socket to connect to: tcp://[host]:[port][path] or unix://[/path/to/socket]")
+	RootCmd.PersistentFlags().StringVarP(&context_name, "context", "c", "", "Name of the context to use for connecting to the engine (overrides the context set with 'jcli context use')")
+	RootCmd.PersistentFlags().DurationVar(&request_timeout, "timeout", time.Minute, "Timeout for each request to the engine, e.g. '30s' (0 disables the timeout)")
+	RootCmd.PersistentFlags().IntVar(&retries, "retries", 3, "Number of times GET/DELETE requests are retried while the engine is unavailable")
+	RootCmd.PersistentFlags().StringVar(&output_format, "format", format_table, "Output format of list and mutation commands: 'table', 'json', 'yaml' or a Go template, e.g. '{{.Id}}'")
+	RootCmd.PersistentFlags().BoolVar(&no_trunc, "no-trunc", false, "Don't truncate the columns of tables to fit the terminal")
+	RootCmd.PersistentFlags().StringSliceVar(&table_columns, "columns", []string{}, "Columns of tables to print, e.g. 'id,name,image'")
+	RootCmd.PersistentFlags().StringVar(&table_sort, "sort", "", "Sort the rows of tables by 'created' (newest first) or 'name'")
+	RootCmd.PersistentFlags().BoolVar(&use_tls, "tls", false, "Use TLS; implied by --tlsverify")
+	RootCmd.PersistentFlags().BoolVar(&tls_verify, "tlsverify", false, "Use TLS and verify the remote")
+	RootCmd.PersistentFlags().StringVar(&tls_cacert, "tlscacert", "", "Trust certs signed only by this CA")
+	RootCmd.PersistentFlags().StringVar(&tls_cert, "tlscert", "", "Path to TLS certificate file")
+	RootCmd.PersistentFlags().StringVar(&tls_key, "tlskey", "", "Path to TLS key file")
 	RootCmd.AddCommand(ContainerCommand())
+	RootCmd.AddCommand(ContextCommand())
 	RootCmd.AddCommand(ImageCommand())
 	RootCmd.AddCommand(NetworkCommand())
+	RootCmd.AddCommand(VolumeCommand())
 }
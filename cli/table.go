@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+const (
+	sort_created = "created"
+	sort_name    = "name"
+
+	table_column_gap = 3
+	// Columns are never truncated to less than this width.
+	table_min_column_width = 5
+	table_ellipsis         = "…"
+)
+
+// terminal_width returns the width of the terminal, and false if stdout is
+// not a terminal, in which case tables are not truncated.
+var terminal_width = func() (int, bool) {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0, false
+	}
+	width, _, err := term.GetSize(fd)
+	if err != nil {
+		return 0, false
+	}
+	return width, true
+}
+
+// Column of a Table. Name is used for selecting columns with --columns.
+type Column struct {
+	Name   string
+	Header string
+}
+
+// Row of a Table, with a cell for each column name. Name and Created are used
+// for sorting the rows with --sort.
+type Row struct {
+	Cells   map[string]string
+	Name    string
+	Created time.Time
+}
+
+// Table prints rows as aligned columns. When stdout is a terminal, the widest
+// columns are truncated with an ellipsis to fit the terminal, unless --no-trunc is given.
+type Table struct {
+	Columns []Column
+	Rows    []Row
+}
+
+func (t *Table) AddRow(row Row) {
+	t.Rows = append(t.Rows, row)
+}
+
+// ValidateTableOptions returns an error if --sort is not a supported sort order.
+// The columns given with --columns are validated by each table.
+func ValidateTableOptions() error {
+	switch table_sort {
+	case "", sort_created, sort_name:
+		return nil
+	}
+	return fmt.Errorf("unsupported sort order '%s', expected '%s' or '%s'", table_sort, sort_created, sort_name)
+}
+
+// Print prints the table on stdout, using the columns and sort order given with --columns and --sort.
+func (t *Table) Print() error {
+	width, is_terminal := terminal_width()
+	if no_trunc || !is_terminal {
+		width = 0
+	}
+	return t.Render(os.Stdout, width)
+}
+
+// Render writes the table to w, truncating the columns to fit in width, unless width is 0.
+func (t *Table) Render(w io.Writer, width int) error {
+	columns, err := t.selected_columns()
+	if err != nil {
+		return err
+	}
+	rows := t.sorted_rows()
+
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = runewidth.StringWidth(column.Header)
+		for _, row := range rows {
+			if cell_width := runewidth.StringWidth(row.Cells[column.Name]); cell_width > widths[i] {
+				widths[i] = cell_width
+			}
+		}
+	}
+	if width > 0 {
+		fit_column_widths(widths, width)
+	}
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
+	}
+	write_table_line(w, header, widths)
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = row.Cells[column.Name]
+		}
+		write_table_line(w, cells, widths)
+	}
+	return nil
+}
+
+func (t *Table) selected_columns() ([]Column, error) {
+	if len(table_columns) == 0 {
+		return t.Columns, nil
+	}
+
+	names := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		names[i] = column.Name
+	}
+	columns := []Column{}
+	for _, name := range table_columns {
+		idx := -1
+		for i := range t.Columns {
+			if t.Columns[i].Name == strings.ToLower(strings.TrimSpace(name)) {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return nil, fmt.Errorf("unknown column '%s', expected one of %s", name, strings.Join(names, ", "))
+		}
+		columns = append(columns, t.Columns[idx])
+	}
+	return columns, nil
+}
+
+// sorted_rows sorts by name in ascending order, or with the most recently
+// created rows first. Otherwise the order of the engine is kept.
+func (t *Table) sorted_rows() []Row {
+	rows := append([]Row(nil), t.Rows...)
+	switch table_sort {
+	case sort_name:
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	case sort_created:
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Created.After(rows[j].Created) })
+	}
+	return rows
+}
+
+// fit_column_widths shrinks the widest columns until the table fits in width.
+func fit_column_widths(widths []int, width int) {
+	total := table_column_gap * (len(widths) - 1)
+	for _, column_width := range widths {
+		total += column_width
+	}
+	for total > width {
+		widest := 0
+		for i := range widths {
+			if widths[i] > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= table_min_column_width {
+			return
+		}
+		widths[widest]--
+		total--
+	}
+}
+
+func write_table_line(w io.Writer, cells []string, widths []int) {
+	var line strings.Builder
+	for i, cell := range cells {
+		cell = runewidth.Truncate(cell, widths[i], table_ellipsis)
+		line.WriteString(cell)
+		if i < len(cells)-1 {
+			line.WriteString(strings.Repeat(" ", widths[i]-runewidth.StringWidth(cell)+table_column_gap))
+		}
+	}
+	fmt.Fprintln(w, line.String())
+}
+
+// created_time parses a timestamp of the engine API.
+func created_time(created *string) time.Time {
+	if created == nil {
+		return time.Time{}
+	}
+	timestamp, _ := time.Parse(time.RFC3339, *created)
+	return timestamp
+}
+
+// created_ago formats a timestamp of the engine API, e.g. "5 minutes ago".
+func created_ago(created *string) string {
+	if created == nil {
+		return ""
+	}
+	return HumanDuration(time.Since(created_time(created))) + " ago"
+}
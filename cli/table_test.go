@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTableRendering(t *testing.T) {
+	now := time.Now()
+	table := Table{Columns: []Column{
+		{Name: "id", Header: "ID"},
+		{Name: "name", Header: "NAME"},
+		{Name: "command", Header: "COMMAND"},
+	}}
+	table.AddRow(Row{Cells: map[string]string{"id": "4b8e2f9c1a3d", "name": "webserver", "command": "/usr/local/sbin/nginx -g daemon off;"}, Name: "webserver", Created: now.Add(-time.Hour)})
+	table.AddRow(Row{Cells: map[string]string{"id": "91ac3e0b7d52", "name": "データベース", "command": "/bin/sh"}, Name: "データベース", Created: now})
+
+	t.Run("columns are aligned", ExpectRenderedTable(table, 0,
+		"ID             NAME           COMMAND\n"+
+			"4b8e2f9c1a3d   webserver      /usr/local/sbin/nginx -g daemon off;\n"+
+			"91ac3e0b7d52   データベース   /bin/sh\n"))
+	t.Run("the widest columns are truncated with an ellipsis to fit", ExpectRenderedTable(table, 40,
+		"ID            NAME          COMMAND\n"+
+			"4b8e2f9c1a…   webserver     /usr/local/…\n"+
+			"91ac3e0b7d…   データベー…   /bin/sh\n"))
+	t.Run("columns can be selected", WithTableOptions([]string{"name", "id"}, "", ExpectRenderedTable(table, 0,
+		"NAME           ID\n"+
+			"webserver      4b8e2f9c1a3d\n"+
+			"データベース   91ac3e0b7d52\n")))
+	t.Run("unknown columns are rejected", WithTableOptions([]string{"size"}, "", func(t *testing.T) {
+		assert.ErrorContains(t, table.Render(&bytes.Buffer{}, 0), "unknown column 'size', expected one of id, name, command")
+	}))
+	t.Run("rows can be sorted by name", WithTableOptions([]string{"id"}, sort_name, ExpectRenderedTable(table, 0,
+		"ID\n4b8e2f9c1a3d\n91ac3e0b7d52\n")))
+	t.Run("rows can be sorted by creation, newest first", WithTableOptions([]string{"id"}, sort_created, ExpectRenderedTable(table, 0,
+		"ID\n91ac3e0b7d52\n4b8e2f9c1a3d\n")))
+	t.Run("unsupported sort orders are rejected", WithTableOptions(nil, "size", func(t *testing.T) {
+		assert.ErrorContains(t, ValidateTableOptions(), "unsupported sort order 'size'")
+	}))
+}
+
+func TestTableTruncation(t *testing.T) {
+	table := Table{Columns: []Column{{Name: "id", Header: "ID"}, {Name: "command", Header: "COMMAND"}}}
+	table.AddRow(Row{Cells: map[string]string{"id": "4b8e2f9c1a3d", "command": "/usr/local/sbin/nginx -g daemon off;"}})
+	truncated := "ID             COMMAND\n4b8e2f9c1a3d   /usr/local/sbin/n…\n"
+	untruncated := "ID             COMMAND\n4b8e2f9c1a3d   /usr/local/sbin/nginx -g daemon off;\n"
+
+	t.Run("output to a terminal is truncated", WithTerminalWidth(33, true, ExpectPrintedTable(table, truncated)))
+	t.Run("--no-trunc disables truncation", WithTerminalWidth(33, true, func(t *testing.T) {
+		no_trunc = true
+		defer func() { no_trunc = false }()
+		ExpectPrintedTable(table, untruncated)(t)
+	}))
+	t.Run("output to a pipe is not truncated", WithTerminalWidth(0, false, ExpectPrintedTable(table, untruncated)))
+}
+
+func ExpectRenderedTable(table Table, width int, expected string) func(*testing.T) {
+	return func(t *testing.T) {
+		var output bytes.Buffer
+		assert.NilError(t, table.Render(&output, width))
+		assert.Equal(t, output.String(), expected)
+	}
+}
+
+func ExpectPrintedTable(table Table, expected string) func(*testing.T) {
+	return func(t *testing.T) {
+		var err error
+		stdout := RunCommandCollectStdOut(func() { err = table.Print() })
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, expected)
+	}
+}
+
+func WithTableOptions(columns []string, sort_by string, f func(*testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		old_columns, old_sort := table_columns, table_sort
+		table_columns, table_sort = columns, sort_by
+		defer func() { table_columns, table_sort = old_columns, old_sort }()
+		f(t)
+	}
+}
+
+func WithTerminalWidth(width int, is_terminal bool, f func(*testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		old_terminal_width := terminal_width
+		terminal_width = func() (int, bool) { return width, is_terminal }
+		defer func() { terminal_width = old_terminal_width }()
+		f(t)
+	}
+}
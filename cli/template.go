@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// A template given with --format is prefixed with the table directive to
+// print the output as a table with headers, e.g. 'table {{.Id}}\t{{.Name}}'.
+const table_directive = "table "
+
+var template_field_pattern = regexp.MustCompile(`\{\{[^}]*?\.([A-Za-z]+)`)
+
+var template_functions = template.FuncMap{
+	"humanDuration": template_human_duration,
+	"join":          template_join,
+	"json":          template_json,
+	"truncate":      template_truncate,
+	"upper":         template_upper,
+}
+
+// ParseFormatTemplate parses a Go template given with --format, and reports
+// whether it uses the table directive. Escaped tabs and newlines in the
+// template are replaced with the characters they represent.
+func ParseFormatTemplate(format string) (*template.Template, bool, error) {
+	is_table := strings.HasPrefix(format, table_directive)
+	format = strings.TrimPrefix(format, table_directive)
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+	tmpl, err := template.New("format").Funcs(template_functions).Parse(format)
+	return tmpl, is_table, err
+}
+
+// TemplateHeader returns the header of a table template. Each tab separated
+// column is named after the first field it uses, e.g. '{{.ImageId}}' gives 'IMAGE ID'.
+func TemplateHeader(format string) string {
+	format = strings.TrimPrefix(format, table_directive)
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+	columns := strings.Split(format, "\t")
+	for i, column := range columns {
+		match := template_field_pattern.FindStringSubmatch(column)
+		if match == nil {
+			columns[i] = ""
+			continue
+		}
+		columns[i] = header_name(match[1])
+	}
+	return strings.Join(columns, "\t")
+}
+
+// header_name splits a field name into upper case words, e.g. 'ImageId' becomes 'IMAGE ID'.
+func header_name(field string) string {
+	var header strings.Builder
+	for i, r := range field {
+		if i > 0 && unicode.IsUpper(r) {
+			header.WriteRune(' ')
+		}
+		header.WriteRune(unicode.ToUpper(r))
+	}
+	return header.String()
+}
+
+// template_value dereferences the pointers used for the fields of the engine API.
+func template_value(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func template_string(value interface{}) string {
+	v := template_value(value)
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// template_human_duration formats the time passed since a timestamp, or a duration.
+func template_human_duration(value interface{}) (string, error) {
+	switch v := template_value(value).(type) {
+	case nil:
+		return "", nil
+	case time.Duration:
+		return HumanDuration(v), nil
+	case time.Time:
+		return HumanDuration(time.Since(v)), nil
+	case string:
+		timestamp, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", err
+		}
+		return HumanDuration(time.Since(timestamp)), nil
+	default:
+		return "", fmt.Errorf("humanDuration: unsupported value of type %T", v)
+	}
+}
+
+func template_join(value interface{}, separator string) (string, error) {
+	switch v := template_value(value).(type) {
+	case nil:
+		return "", nil
+	case []string:
+		return strings.Join(v, separator), nil
+	default:
+		return "", fmt.Errorf("join: expected a list of strings, got %T", v)
+	}
+}
+
+func template_json(value interface{}) (string, error) {
+	output, err := json.Marshal(value)
+	return string(output), err
+}
+
+func template_truncate(value interface{}, length int) string {
+	runes := []rune(template_string(value))
+	if len(runes) <= length {
+		return string(runes)
+	}
+	return string(runes[:length])
+}
+
+func template_upper(value interface{}) string {
+	return strings.ToUpper(template_string(value))
+}
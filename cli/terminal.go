@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+const default_detach_keys = "ctrl-p,ctrl-q"
+
+// ParseDetachKeys parses a comma separated sequence of keys for detaching from
+// a container, where each key is a single character or ctrl-<value> with
+// value being a letter or one of @, [, \\, ], ^ and _.
+func ParseDetachKeys(keys string) ([]byte, error) {
+	if keys == "" {
+		keys = default_detach_keys
+	}
+	sequence := []byte{}
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+		switch {
+		case len(key) == 1:
+			sequence = append(sequence, key[0])
+		case len(key) == len("ctrl-x") && strings.HasPrefix(strings.ToLower(key), "ctrl-"):
+			code, valid := control_code(key[len(key)-1])
+			if !valid {
+				return nil, fmt.Errorf("invalid detach key '%s'", key)
+			}
+			sequence = append(sequence, code)
+		default:
+			return nil, fmt.Errorf("invalid detach key '%s', expected a single character or ctrl-<value>", key)
+		}
+	}
+	return sequence, nil
+}
+
+// control_code returns the code of ctrl-c, e.g. 0x10 for ctrl-p.
+func control_code(c byte) (byte, bool) {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return c - 'a' + 1, true
+	case c >= 'A' && c <= 'Z':
+		return c - 'A' + 1, true
+	case c >= '@' && c <= '_':
+		// @, [, \, ], ^ and _
+		return c - '@', true
+	}
+	return 0, false
+}
+
+// terminal_size returns the size of the terminal that jcli prints to.
+var terminal_size = func() (TerminalSize, error) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	return TerminalSize{Width: width, Height: height}, err
+}
+
+// is_terminal returns true if file is a terminal.
+var is_terminal = func(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
+
+// make_raw puts the terminal of file in raw mode, and returns a function that
+// restores its previous state.
+var make_raw = func(file *os.File) (func(), error) {
+	fd := int(file.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = term.Restore(fd, state) }, nil
+}
+
+// ValidateTerminal returns an error if file is not a terminal.
+func ValidateTerminal(file *os.File) error {
+	if !is_terminal(file) {
+		return errors.New("the input device is not a TTY")
+	}
+	return nil
+}
+
+// MakeRawTerminal puts the terminal of file in raw mode, and returns a
+// function that restores its previous state.
+func MakeRawTerminal(file *os.File) (func(), error) {
+	if err := ValidateTerminal(file); err != nil {
+		return nil, err
+	}
+	restore, err := make_raw(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not put the terminal in raw mode: %w", err)
+	}
+	return restore, nil
+}
+
+// MonitorTerminalSize sends the size of the terminal to an attached container.
+// It is sent again in the background every time the terminal is resized,
+// until stop is closed.
+func MonitorTerminalSize(sender *WSSender, stop <-chan struct{}) {
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	send_terminal_size(sender)
+
+	go func() {
+		defer signal.Stop(resized)
+		for {
+			select {
+			case <-resized:
+				send_terminal_size(sender)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func send_terminal_size(sender *WSSender) {
+	if size, err := terminal_size(); err == nil {
+		_ = sender.SendResize(size)
+	}
+}
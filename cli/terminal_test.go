@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTerminalResize(t *testing.T) {
+	if fake_engine == nil {
+		t.Skip("the terminal size is only reported by the fake engine")
+	}
+	var container_id string
+	t.Run("create a container that sleeps when started", SuccesfullyCreateContainer(t, &container_id, "resized", "base", []string{"/bin/sleep", "10"}))
+
+	var size_lock sync.Mutex
+	size := TerminalSize{Width: 80, Height: 24}
+	old_terminal_size := terminal_size
+	terminal_size = func() (TerminalSize, error) {
+		size_lock.Lock()
+		defer size_lock.Unlock()
+		return size, nil
+	}
+	defer func() { terminal_size = old_terminal_size }()
+
+	done, _, ws, err := Dial(fmt.Sprintf(ws_container_attach, container_id), nil)
+	assert.NilError(t, err)
+	go func() {
+		// Reading is required for handling control frames.
+		defer close(done)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	MonitorTerminalSize(NewWSSender(ws), done)
+
+	t.Run("the size is sent when attaching", ExpectTerminalSize(container_id, 80, 24))
+	t.Run("the size is sent again when the terminal is resized", func(t *testing.T) {
+		size_lock.Lock()
+		size = TerminalSize{Width: 132, Height: 43}
+		size_lock.Unlock()
+		assert.NilError(t, syscall.Kill(os.Getpid(), syscall.SIGWINCH))
+		ExpectTerminalSize(container_id, 132, 43)(t)
+	})
+
+	ws.Close()
+	<-done
+	t.Run("remove container", SuccesfullyRemoveContainer(t, container_id))
+}
+
+func ExpectTerminalSize(container_id string, width int, height int) func(*testing.T) {
+	return func(t *testing.T) {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			actual_width, actual_height, _ := fake_engine.TerminalSize(container_id)
+			if actual_width == width && actual_height == height {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("terminal size is %dx%d, expected %dx%d", actual_width, actual_height, width, height)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// WithFakeTerminal treats stdin as a terminal while running f, e.g. a pipe
+// created by WithStdin.
+func WithFakeTerminal(f func(*testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		old_is_terminal, old_make_raw, old_terminal_size := is_terminal, make_raw, terminal_size
+		is_terminal = func(*os.File) bool { return true }
+		make_raw = func(*os.File) (func(), error) { return func() {}, nil }
+		terminal_size = func() (TerminalSize, error) { return TerminalSize{Width: 80, Height: 24}, nil }
+		defer func() { is_terminal, make_raw, terminal_size = old_is_terminal, old_make_raw, old_terminal_size }()
+		f(t)
+	}
+}
+
+func TestParseDetachKeys(t *testing.T) {
+	t.Run("the default detach keys", ExpectDetachKeys("", []byte{0x10, 0x11}))
+	t.Run("control keys", ExpectDetachKeys("ctrl-a,ctrl-@,ctrl-[,ctrl-_", []byte{0x01, 0x00, 0x1b, 0x1f}))
+	t.Run("single characters", ExpectDetachKeys("ctrl-X, q", []byte{0x18, 'q'}))
+	t.Run("invalid keys", func(t *testing.T) {
+		_, err := ParseDetachKeys("ctrl-p,alt-q")
+		assert.ErrorContains(t, err, "invalid detach key 'alt-q', expected a single character or ctrl-<value>")
+		_, err = ParseDetachKeys("ctrl-1")
+		assert.ErrorContains(t, err, "invalid detach key 'ctrl-1'")
+	})
+}
+
+func ExpectDetachKeys(keys string, expected []byte) func(*testing.T) {
+	return func(t *testing.T) {
+		sequence, err := ParseDetachKeys(keys)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, sequence, expected)
+	}
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSConfig returns the TLS configuration used for connecting to the engine
+// of the context, or nil if TLS is not enabled. With --tls the certificate of
+// the engine is not verified, which requires --tlsverify.
+func (c *EngineContext) TLSConfig() (*tls.Config, error) {
+	if !c.TLS && !c.TLSVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: !c.TLSVerify,
+	}
+
+	if c.TLSCACert != "" {
+		ca_cert, err := ioutil.ReadFile(c.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA certificate: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca_cert) {
+			return nil, errors.New("no valid certificates found in " + c.TLSCACert)
+		}
+	}
+
+	if c.TLSCert != "" || c.TLSKey != "" {
+		if c.TLSCert == "" || c.TLSKey == "" {
+			return nil, errors.New("both a TLS certificate and key must be given for client authentication")
+		}
+		certificate, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
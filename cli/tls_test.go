@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTLSConnections(t *testing.T) {
+	server := httptest.NewTLSServer(NetworkListAndAttachHandler())
+	defer server.Close()
+	ca_cert := WriteServerCACert(t, server)
+	engine_host := strings.Replace(server.URL, "https://", "tcp://", 1)
+
+	t.Run("plain http is rejected by the engine", WithEngineContext(EngineContext{Host: engine_host}, func(t *testing.T) {
+		_, err := NetworkList()
+		assert.Assert(t, err != nil)
+	}))
+	t.Run("--tlsverify without the CA of the engine fails", WithEngineContext(EngineContext{Host: engine_host, TLSVerify: true}, func(t *testing.T) {
+		_, err := NetworkList()
+		assert.ErrorContains(t, err, "certificate")
+	}))
+	t.Run("--tls without verification succeeds", WithEngineContext(EngineContext{Host: engine_host, TLS: true}, ExpectNetworkListOverTLS))
+	t.Run("--tlsverify with the CA of the engine succeeds", WithEngineContext(EngineContext{Host: engine_host, TLSVerify: true, TLSCACert: ca_cert}, ExpectNetworkListOverTLS))
+	t.Run("websockets use wss", WithEngineContext(EngineContext{Host: engine_host, TLSVerify: true, TLSCACert: ca_cert}, func(t *testing.T) {
+		endpoint, err := CurrentEndpoint()
+		assert.NilError(t, err)
+		ws_url := endpoint.WebsocketURL("/containers/abc/attach", nil)
+		assert.Assert(t, strings.HasPrefix(ws_url, "wss://"))
+		ws, _, err := endpoint.WebsocketDialer().Dial(ws_url, nil)
+		assert.NilError(t, err)
+		defer ws.Close()
+		_, message, err := ws.ReadMessage()
+		assert.NilError(t, err)
+		assert.Equal(t, string(message), "ok:")
+	}))
+}
+
+func TestMutualTLSConnections(t *testing.T) {
+	client_cert, client_key, client_pool := GenerateClientCertificate(t)
+	server := httptest.NewUnstartedServer(NetworkListAndAttachHandler())
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: client_pool}
+	server.StartTLS()
+	defer server.Close()
+	ca_cert := WriteServerCACert(t, server)
+	engine_host := strings.Replace(server.URL, "https://", "tcp://", 1)
+
+	t.Run("mutual TLS without a client certificate fails", WithEngineContext(EngineContext{Host: engine_host, TLSVerify: true, TLSCACert: ca_cert}, func(t *testing.T) {
+		_, err := NetworkList()
+		assert.Assert(t, err != nil)
+	}))
+	t.Run("mutual TLS with a client certificate succeeds", WithEngineContext(EngineContext{Host: engine_host, TLSVerify: true, TLSCACert: ca_cert, TLSCert: client_cert, TLSKey: client_key}, ExpectNetworkListOverTLS))
+	t.Run("a client certificate requires a key", func(t *testing.T) {
+		_, err := (&EngineContext{TLSVerify: true, TLSCert: client_cert}).TLSConfig()
+		assert.ErrorContains(t, err, "both a TLS certificate and key")
+	})
+}
+
+func ExpectNetworkListOverTLS(t *testing.T) {
+	response, err := NetworkList()
+	assert.NilError(t, err)
+	assert.Equal(t, *(*response.JSON200)[0].Name, "default")
+}
+
+// WithEngineContext runs f with the global flags set to the settings of engine_context.
+func WithEngineContext(engine_context EngineContext, f func(*testing.T)) func(*testing.T) {
+	return func(t *testing.T) {
+		old_host, old_tls, old_tls_verify := host, use_tls, tls_verify
+		old_tls_cacert, old_tls_cert, old_tls_key := tls_cacert, tls_cert, tls_key
+		defer func() {
+			host, use_tls, tls_verify = old_host, old_tls, old_tls_verify
+			tls_cacert, tls_cert, tls_key = old_tls_cacert, old_tls_cert, old_tls_key
+		}()
+		host, use_tls, tls_verify = engine_context.Host, engine_context.TLS, engine_context.TLSVerify
+		tls_cacert, tls_cert, tls_key = engine_context.TLSCACert, engine_context.TLSCert, engine_context.TLSKey
+		f(t)
+	}
+}
+
+func WriteServerCACert(t *testing.T, server *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NilError(t, os.WriteFile(path, cert, 0644))
+	return path
+}
+
+// GenerateClientCertificate creates a self-signed client certificate and
+// returns the paths of the certificate and key, together with a pool
+// containing the certificate for the server to verify clients against.
+func GenerateClientCertificate(t *testing.T) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "jcli"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	key_der, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+
+	dir := t.TempDir()
+	cert_path := filepath.Join(dir, "cert.pem")
+	key_path := filepath.Join(dir, "key.pem")
+	assert.NilError(t, os.WriteFile(cert_path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.NilError(t, os.WriteFile(key_path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key_der}), 0600))
+
+	certificate, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return cert_path, key_path, pool
+}
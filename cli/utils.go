@@ -1,36 +1,85 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	Openapi "jcli/client"
-	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
-func NewHTTPClient() *Openapi.ClientWithResponses {
-	client, err := Openapi.NewClientWithResponses(jocker_engine_url)
+// NewHTTPClient returns a client for the engine of the current connection.
+func NewHTTPClient() (*Openapi.ClientWithResponses, error) {
+	endpoint, credentials, err := CurrentConnection()
 	if err != nil {
-		fmt.Println("Internal error: ", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("could not configure the connection to jocker engine: %w", err)
+	}
+
+	var doer Openapi.HttpRequestDoer = endpoint.HTTPClient()
+	if debug {
+		doer = &TracingDoer{Doer: doer}
+	}
+	if retries > 0 {
+		doer = &RetryDoer{Doer: doer, Retries: retries, BaseDelay: retry_base_delay, MaxDelay: retry_max_delay}
+	}
+
+	options := []Openapi.ClientOption{Openapi.WithHTTPClient(doer)}
+	if credentials != nil {
+		options = append(options, Openapi.WithRequestEditorFn(AuthRequestEditor(credentials)))
+	}
+
+	client, err := Openapi.NewClientWithResponses(endpoint.HTTPBaseURL(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %w", err)
+	}
+	return client, nil
+}
+
+// RequestContext returns the context used for a single request to the engine.
+// It is cancelled when jcli is interrupted or when --timeout has passed.
+func RequestContext() (context.Context, context.CancelFunc) {
+	return RequestContextFrom(root_ctx)
+}
+
+// RequestContextFrom returns the context used for a single request to the
+// engine, which is cancelled when parent is done or when --timeout has passed.
+// Requests that must not be cancelled when jcli is interrupted, e.g. for
+// cleaning up after the user pressed Ctrl-C, use context.Background() as parent.
+func RequestContextFrom(parent context.Context) (context.Context, context.CancelFunc) {
+	if request_timeout > 0 {
+		return context.WithTimeout(parent, request_timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// request_error describes why a request to the engine did not get a response.
+func request_error(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("request cancelled: %w", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return &StatusError{Status: ExitEngine, Err: fmt.Errorf("request timed out after %s: %w", request_timeout, err)}
+	default:
+		return &StatusError{Status: ExitEngine, Err: fmt.Errorf("could not connect to jocker engine daemon: %w", err)}
 	}
-	return client
 }
 
 type ResponseWithCode interface {
 	StatusCode() int
 }
 
-func verify_response(response ResponseWithCode, expected_status int, err error) error {
+// verify_response returns the error of a request for operation, which is an
+// *EngineError if the engine did not respond with expected_status.
+func verify_response(operation string, response ResponseWithCode, expected_status int, err error) error {
 	if err != nil {
-		fmt.Println("Could not connect to jocker engine daemon: ", err)
-		return err
+		return request_error(err)
 	}
 
 	if response.StatusCode() != expected_status {
-		fmt.Println("Jocker engine returned unsuccesful statuscode: ", response.StatusCode())
-		return errors.New("unsuccesful statuscode")
+		return NewEngineError(operation, response)
 	}
 	return nil
 }
@@ -62,16 +111,46 @@ func HumanDuration(d time.Duration) string {
 	return fmt.Sprintf("%d years", int(d.Hours())/24/365)
 }
 
-func Cell(word string, max_len int) string {
-	word_length := len(word)
+var human_duration_units = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+	"d":      24 * time.Hour,
+	"w":      7 * 24 * time.Hour,
+}
+
+var human_duration_pattern = regexp.MustCompile(`^(\d+|an?)\s*([a-z]+?)s?$`)
 
-	if word_length <= max_len {
-		return word + Sp(max_len-word_length) + Sp(2)
-	} else {
-		return word[:max_len] + ".."
+// ParseHumanDuration is the inverse of HumanDuration, e.g. "About an hour ago"
+// and "3 days" are parsed as one hour and 72 hours. Go durations such as "90m"
+// and "3d"/"2w" for days and weeks are accepted as well.
+func ParseHumanDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	text := strings.ToLower(strings.TrimSpace(s))
+	text = strings.TrimSpace(strings.TrimSuffix(text, " ago"))
+	text = strings.TrimPrefix(text, "about ")
+	if text == "less than a second" {
+		return 0, nil
 	}
-}
 
-func Sp(n int) string {
-	return strings.Repeat(" ", n)
+	match := human_duration_pattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+	unit, known := human_duration_units[match[2]]
+	if !known {
+		return 0, fmt.Errorf("invalid duration '%s': unknown unit '%s'", s, match[2])
+	}
+	count := 1
+	if match[1] != "a" && match[1] != "an" {
+		count, _ = strconv.Atoi(match[1])
+	}
+	return time.Duration(count) * unit, nil
 }
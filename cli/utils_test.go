@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRequestCancellation(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	t.Run("requests to a hanging engine time out", WithHost(server.URL, func(t *testing.T) {
+		old_timeout := request_timeout
+		request_timeout = 50 * time.Millisecond
+		defer func() { request_timeout = old_timeout }()
+
+		_, err := NetworkList()
+		assert.ErrorContains(t, err, "request timed out after 50ms")
+		assert.Assert(t, errors.Is(err, context.DeadlineExceeded))
+	}))
+
+	t.Run("requests are cancelled when jcli is interrupted", WithHost(server.URL, func(t *testing.T) {
+		old_root_ctx := root_ctx
+		ctx, cancel := context.WithCancel(context.Background())
+		root_ctx = ctx
+		defer func() { root_ctx = old_root_ctx }()
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		_, err := GetContainerList(true)
+		assert.ErrorContains(t, err, "request cancelled")
+		assert.Assert(t, errors.Is(err, context.Canceled))
+	}))
+}
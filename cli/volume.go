@@ -1 +1,151 @@
 package cli
+
+import (
+	Openapi "jcli/client"
+
+	"github.com/spf13/cobra"
+)
+
+func VolumeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "volume",
+		Short:                 "Manage volumes",
+		Long:                  `Manage volumes`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(VolumeCreateCommand())
+	cmd.AddCommand(VolumeListCommand())
+	cmd.AddCommand(VolumeRemoveCommand())
+	return cmd
+}
+
+func VolumeCreateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "create VOLUME",
+		Short:                 "Create a volume",
+		Long:                  `Create a volume`,
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := VolumeCreate(args[0]); err != nil {
+				return err
+			}
+			// The engine responds with 204 No Content, so the volume is identified by its name.
+			return PrintIdResponse(&Openapi.IdResponse{Id: args[0]})
+		},
+	}
+	return cmd
+}
+
+func VolumeCreate(name string) (*Openapi.VolumeCreateResponse, error) {
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := RequestContext()
+	defer cancel()
+
+	response, err := client.VolumeCreateWithResponse(ctx, Openapi.VolumeCreateJSONRequestBody{Name: name})
+	err = verify_response("volume create", response, 204, err)
+	return response, err
+}
+
+func VolumeListCommand() *cobra.Command {
+	var quiet bool
+
+	cmd := &cobra.Command{
+		Use:                   "ls",
+		Short:                 "List volumes",
+		Long:                  `List volumes`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			response, err := VolumeList()
+			if err != nil {
+				return err
+			}
+			if quiet {
+				ids := make([]string, len(*response.JSON200))
+				for i, volume := range *response.JSON200 {
+					ids[i] = *volume.Name
+				}
+				return PrintQuiet(ids)
+			}
+			return PrintOutput(response.JSON200, func() error { return PrintVolumeList(response.JSON200) })
+		},
+	}
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only display volume names")
+	return cmd
+}
+
+func VolumeList() (*Openapi.VolumeListResponse, error) {
+	client, err := NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := RequestContext()
+	defer cancel()
+
+	response, err := client.VolumeListWithResponse(ctx)
+	err = verify_response("volume list", response, 200, err)
+	return response, err
+}
+
+func PrintVolumeList(volumes *[]Openapi.VolumeSummary) error {
+	table := Table{Columns: []Column{
+		{Name: "name", Header: "VOLUME NAME"},
+		{Name: "created", Header: "CREATED"},
+	}}
+	for _, volume := range *volumes {
+		table.AddRow(Row{
+			Cells:   map[string]string{"name": *volume.Name, "created": created_ago(volume.Created)},
+			Name:    *volume.Name,
+			Created: created_time(volume.Created),
+		})
+	}
+	return table.Print()
+}
+
+func VolumeRemoveCommand() *cobra.Command {
+	var parallel int
+
+	cmd := &cobra.Command{
+		Use:                   "rm [OPTIONS] VOLUME [VOLUME...]",
+		Short:                 "Remove one or more volumes",
+		Long:                  `Remove one or more volumes`,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validate_parallel(parallel); err != nil {
+				return err
+			}
+			client, err := NewHTTPClient()
+			if err != nil {
+				return err
+			}
+			responses, errs := RemoveVolumes(client, args, parallel)
+			return print_results(errs, func(idx int) Openapi.IdResponse { return *responses[idx].JSON200 })
+		},
+	}
+	cmd.Flags().IntVar(&parallel, "parallel", default_parallel, "Maximum number of volumes to remove concurrently")
+	return cmd
+}
+
+// RemoveVolumes removes every volume, with at most parallel requests to the
+// engine at a time. The responses and errors are in the order of names.
+func RemoveVolumes(client *Openapi.ClientWithResponses, names []string, parallel int) ([]*Openapi.VolumeRemoveResponse, []error) {
+	errs := make([]error, len(names))
+	responses := make([]*Openapi.VolumeRemoveResponse, len(names))
+	run_parallel(len(names), parallel, func(idx int) {
+		ctx, cancel := RequestContext()
+		defer cancel()
+		response, err := client.VolumeRemoveWithResponse(ctx, names[idx])
+		errs[idx] = verify_response("volume remove", response, 200, err)
+		responses[idx] = response
+	})
+	return responses, errs
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestVolumeRemove(t *testing.T) {
+	for _, name := range []string{"vol1", "vol2", "vol3"} {
+		_, err := ExecuteRootCommand("volume", "create", name)
+		assert.NilError(t, err)
+	}
+	t.Run("removed volumes are printed in the order given", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("volume", "rm", "--parallel", "2", "vol3", "nonexisting", "vol1")
+		assert.Equal(t, ExitCode(err), ExitEngine)
+		assert.Equal(t, stdout, "vol3\nvol1\n")
+	})
+	t.Run("--parallel must be positive", func(t *testing.T) {
+		_, err := ExecuteRootCommand("volume", "rm", "--parallel", "0", "vol2")
+		assert.ErrorContains(t, err, "invalid value for --parallel")
+	})
+	t.Run("remove the remaining volume", func(t *testing.T) {
+		stdout, err := ExecuteRootCommand("volume", "rm", "vol2")
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, "vol2\n")
+		response, err := VolumeList()
+		assert.NilError(t, err)
+		assert.Equal(t, len(*response.JSON200), 0)
+	})
+}
@@ -1,69 +1,214 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"net/url"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-func Dial(endpoint string) (chan struct{}, chan os.Signal, *websocket.Conn) {
-	ws, _, err := websocket.DefaultDialer.Dial(endpoint, nil)
+const (
+	// The engine closes websockets with code 1000 and a reason starting with
+	// ws_exit_prefix when the operation, e.g. an attached container, has finished.
+	ws_exit_prefix = "exit:"
+	// Frames sent to an attached container with input for its stdin, and
+	// when there is no more input.
+	ws_input_prefix = "io:"
+	ws_input_eof    = "eof:"
+	// Frames sent to an attached container when the size of the terminal changes.
+	ws_resize_prefix = "resize:"
+)
+
+// TerminalSize is sent to an attached container in a resize frame, e.g.
+// `resize:{"width":80,"height":24}`, with the size in characters.
+type TerminalSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// WSSender sends frames on a websocket from several goroutines.
+type WSSender struct {
+	ws   *websocket.Conn
+	lock sync.Mutex
+}
+
+func NewWSSender(ws *websocket.Conn) *WSSender {
+	return &WSSender{ws: ws}
+}
+
+func (s *WSSender) Send(message_type int, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	debug_log("--> websocket frame (type %d): %q", message_type, data)
+	return s.ws.WriteMessage(message_type, data)
+}
+
+// SendResize sends a resize frame with the size of the terminal.
+func (s *WSSender) SendResize(size TerminalSize) error {
+	payload, err := json.Marshal(size)
+	if err != nil {
+		return err
+	}
+	return s.Send(websocket.TextMessage, append([]byte(ws_resize_prefix), payload...))
+}
+
+// ForwardInput sends everything read from input to the attached container,
+// followed by ws_input_eof when input is closed. It returns true if the
+// detach_keys sequence was read, which is not sent to the container.
+func ForwardInput(sender *WSSender, input io.Reader, detach_keys []byte) bool {
+	buf := make([]byte, 32*1024)
+	// The number of detach keys read so far, which are held back until the
+	// sequence is either completed or broken.
+	matched := 0
+	for {
+		n, err := input.Read(buf)
+		frame := []byte(ws_input_prefix)
+		for _, b := range buf[:n] {
+			if len(detach_keys) > 0 && b == detach_keys[matched] {
+				matched++
+				if matched == len(detach_keys) {
+					if len(frame) > len(ws_input_prefix) {
+						_ = sender.Send(websocket.BinaryMessage, frame)
+					}
+					return true
+				}
+				continue
+			}
+			frame = append(frame, detach_keys[:matched]...)
+			matched = 0
+			if len(detach_keys) > 0 && b == detach_keys[0] {
+				matched = 1
+				continue
+			}
+			frame = append(frame, b)
+		}
+		if err != nil {
+			frame = append(frame, detach_keys[:matched]...)
+		}
+		if len(frame) > len(ws_input_prefix) {
+			if sender.Send(websocket.BinaryMessage, frame) != nil {
+				return false
+			}
+		}
+		if err != nil {
+			_ = sender.Send(websocket.TextMessage, []byte(ws_input_eof))
+			return false
+		}
+	}
+}
+
+func Dial(path string, query url.Values) (chan struct{}, chan os.Signal, *websocket.Conn, error) {
+	endpoint, credentials, err := CurrentConnection()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid engine connection: %w", err)
+	}
+
+	ctx, cancel := RequestContext()
+	defer cancel()
+
+	ws_url := endpoint.WebsocketURL(path, query)
+	headers := AuthHeaders(credentials)
+	debug_log("--> websocket %s\n%s", ws_url, format_headers(headers))
+
+	ws, response, err := endpoint.WebsocketDialer().DialContext(ctx, ws_url, headers)
+	if err == websocket.ErrBadHandshake {
+		return nil, nil, nil, &EngineError{Operation: "websocket handshake", StatusCode: response.StatusCode, RequestID: response.Header.Get(request_id_header)}
+	}
 	if err != nil {
-		log.Fatal("dial:", err)
+		return nil, nil, nil, request_error(err)
 	}
+	debug_log("<-- websocket %s\n%s", response.Status, format_headers(response.Header))
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
 	done := make(chan struct{})
-	return done, interrupt, ws
+	return done, interrupt, ws, nil
 }
 
-func AwaitDoneOrUserInterrupt(done chan struct{}, interrupt chan os.Signal, ws *websocket.Conn) {
+// ErrDetached is returned when the user detached from a container, which keeps running.
+var ErrDetached = errors.New("detached from container")
+
+// AwaitDoneOrUserInterrupt waits until the websocket is closed. If the user
+// interrupts jcli, or detached is closed, the websocket is closed first. A nil
+// detached channel is never closed.
+func AwaitDoneOrUserInterrupt(done chan struct{}, interrupt chan os.Signal, ws *websocket.Conn, detached <-chan struct{}) error {
 	defer ws.Close()
 	for {
 		select {
 		case <-done:
-			return
+			return nil
 		case <-interrupt:
-			fmt.Println("Interrupted by user")
 			TryGracefulWSDisconnectconnect(done, ws)
-			return
+			return errors.New("interrupted by user")
+		case <-detached:
+			TryGracefulWSDisconnectconnect(done, ws)
+			return ErrDetached
 		}
 	}
 }
 
-func ListenForWSMessages(done chan struct{}, ws *websocket.Conn) {
+// WSClose describes how the engine closed a websocket.
+type WSClose struct {
+	// ExitMessage is the exit message of the engine, e.g. "container 4b8e2f9c1a3d stopped".
+	ExitMessage string
+	// Err is set if the websocket was closed unexpectedly.
+	Err error
+}
+
+// ListenForWSMessages prints the messages received on ws until it is closed.
+// How the websocket was closed is stored in closed if it is not nil, and
+// printed otherwise. It is set before done is closed.
+func ListenForWSMessages(done chan struct{}, ws *websocket.Conn, closed *WSClose) {
 	defer close(done)
 	for {
-		_, message, err := ws.ReadMessage()
+		message_type, message, err := ws.ReadMessage()
 		if err != nil {
-			if strings.HasPrefix(err.Error(), succesful_ws_exit) {
-				fmt.Println(err.Error()[len(succesful_ws_exit):])
-			} else {
-				fmt.Println("websocket closed unexpectedly:", err.Error())
+			debug_log("<-- websocket closed: %s", err)
+			// A normal closure without an exit message is the reply to jcli
+			// closing the websocket, e.g. when detaching.
+			var close_error *websocket.CloseError
+			is_normal_closure := errors.As(err, &close_error) && close_error.Code == websocket.CloseNormalClosure
+			result := WSClose{}
+			if is_normal_closure && strings.HasPrefix(close_error.Text, ws_exit_prefix) {
+				result.ExitMessage = close_error.Text[len(ws_exit_prefix):]
+			} else if !is_normal_closure {
+				result.Err = fmt.Errorf("websocket closed unexpectedly: %w", err)
+			}
+			if closed != nil {
+				*closed = result
+			} else if result.ExitMessage != "" {
+				fmt.Println(result.ExitMessage)
+			} else if result.Err != nil {
+				fmt.Println(result.Err)
 			}
 			return
 		}
+		debug_log("<-- websocket frame (type %d): %q", message_type, message)
 		msg := string(message)
-		if msg[:3] == "ok:" {
+		if strings.HasPrefix(msg, "ok:") {
 			// First message receieved when the ws is succesfully established.
 			continue
 		}
-		if msg[:3] == "io:" {
-			fmt.Print(msg[3:])
+		if strings.HasPrefix(msg, "io:") {
+			fmt.Print(msg[len("io:"):])
 		}
 	}
 }
 
 func TryGracefulWSDisconnectconnect(done chan struct{}, ws *websocket.Conn) {
-	_ = ws.WriteMessage(
+	// Unlike WriteMessage, WriteControl can be called concurrently with a WSSender.
+	_ = ws.WriteControl(
 		websocket.CloseMessage,
 		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second),
 	)
 	select {
 	case <-done:
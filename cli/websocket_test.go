@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gotest.tools/v3/assert"
+)
+
+func TestListenForWSMessages(t *testing.T) {
+	t.Run("frames without a known prefix are ignored", ExpectWSOutput(
+		[]string{"ok:", "", "x", "io", "io:hello\n"},
+		WSClose{ExitMessage: "container abc stopped"},
+		"hello\n",
+	))
+}
+
+func ExpectWSOutput(frames []string, expected WSClose, expected_stdout string) func(*testing.T) {
+	return func(t *testing.T) {
+		server := httptest.NewServer(AttachHandler(frames, expected.ExitMessage))
+		defer server.Close()
+		WithHost(server.URL, func(t *testing.T) {
+			done, _, ws, err := Dial("/containers/abc/attach", nil)
+			assert.NilError(t, err)
+			defer ws.Close()
+			var closed WSClose
+			stdout := RunCommandCollectStdOut(func() { ListenForWSMessages(done, ws, &closed) })
+			assert.Equal(t, stdout, expected_stdout)
+			assert.DeepEqual(t, closed, expected)
+		})(t)
+	}
+}
+
+// AttachHandler serves a websocket that sends frames, and is then closed with
+// exit_message like the engine does when a container stops.
+func AttachHandler(frames []string, exit_message string) http.Handler {
+	upgrader := websocket.Upgrader{}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for _, frame := range frames {
+			_ = ws.WriteMessage(websocket.TextMessage, []byte(frame))
+		}
+		reason := websocket.FormatCloseMessage(websocket.CloseNormalClosure, ws_exit_prefix+exit_message)
+		_ = ws.WriteControl(websocket.CloseMessage, reason, time.Now().Add(time.Second))
+	})
+}
@@ -0,0 +1,179 @@
+package fakeengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// attachment is a websocket attached to a container. The engine sends "ok:"
+// when the websocket has been attached, "io:<output>" for everything the
+// container writes, and closes the websocket with code 1000 and the reason
+// "exit:container <id> stopped" when the container stops. Like the real
+// engine, the exit code of the container is not reported. Clients send
+// "io:<input>" for the stdin of the container, "eof:" to close it, and
+// `resize:{"width":80,"height":24}` when the size of their terminal changes.
+type attachment struct {
+	ws         *websocket.Conn
+	write_lock sync.Mutex
+}
+
+func (a *attachment) send(message string) {
+	a.write_lock.Lock()
+	defer a.write_lock.Unlock()
+	_ = a.ws.WriteMessage(websocket.TextMessage, []byte(message))
+}
+
+func (a *attachment) close(code int, reason string) {
+	a.write_lock.Lock()
+	defer a.write_lock.Unlock()
+	_ = a.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	a.ws.Close()
+}
+
+func (a *attachment) close_with_exit(container_id string) {
+	a.close(websocket.CloseNormalClosure, "exit:container "+container_id+" stopped")
+}
+
+// ContainerAttach serves GET /containers/{container_id}/attach.
+func (e *Engine) ContainerAttach(ctx echo.Context) error {
+	e.attach_lock.RLock()
+	ws, err := upgrader.Upgrade(ctx.Response(), ctx.Request(), nil)
+	if err != nil {
+		e.attach_lock.RUnlock()
+		return nil
+	}
+	a := &attachment{ws: ws}
+
+	e.mu.Lock()
+	c := e.find_container(ctx.Param("container_id"))
+	e.mu.Unlock()
+	if c == nil {
+		e.attach_lock.RUnlock()
+		a.close(websocket.CloseInternalServerErr, "error:no such container")
+		return nil
+	}
+
+	a.send("ok:")
+	e.mu.Lock()
+	c.attached = append(c.attached, a)
+	e.mu.Unlock()
+	e.attach_lock.RUnlock()
+
+	// Reading is also required for handling control frames, e.g. when the client disconnects.
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		if strings.HasPrefix(string(message), "resize:") {
+			size := &terminal_size{}
+			if json.Unmarshal(message[len("resize:"):], size) == nil {
+				e.mu.Lock()
+				c.terminal_size = size
+				e.mu.Unlock()
+			}
+			continue
+		}
+
+		e.mu.Lock()
+		stdin := c.stdin
+		e.mu.Unlock()
+		switch {
+		case stdin == nil:
+		case strings.HasPrefix(string(message), "io:"):
+			_, _ = stdin.Write(message[len("io:"):])
+		case string(message) == "eof:":
+			stdin.Close()
+		}
+	}
+	e.detach(c, a)
+	ws.Close()
+	return nil
+}
+
+func (e *Engine) detach(c *container, a *attachment) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := range c.attached {
+		if c.attached[i] == a {
+			c.attached = append(c.attached[:i], c.attached[i+1:]...)
+			return
+		}
+	}
+}
+
+type terminal_size struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// input is the stdin of a process. Writes never block, so a process that does
+// not read its stdin cannot block the websocket that the input is sent on.
+type input struct {
+	lock   sync.Mutex
+	ready  *sync.Cond
+	buf    bytes.Buffer
+	closed bool
+}
+
+func new_input() *input {
+	in := &input{}
+	in.ready = sync.NewCond(&in.lock)
+	return in
+}
+
+func (in *input) Read(p []byte) (int, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	for in.buf.Len() == 0 && !in.closed {
+		in.ready.Wait()
+	}
+	if in.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return in.buf.Read(p)
+}
+
+func (in *input) Write(p []byte) (int, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	if in.closed {
+		return 0, io.ErrClosedPipe
+	}
+	in.ready.Broadcast()
+	return in.buf.Write(p)
+}
+
+func (in *input) Close() error {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	in.closed = true
+	in.ready.Broadcast()
+	return nil
+}
+
+// output sends everything written by a process to the websockets attached to its container.
+type output struct {
+	engine    *Engine
+	container *container
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.engine.mu.Lock()
+	attached := append([]*attachment(nil), o.container.attached...)
+	o.engine.mu.Unlock()
+
+	for _, a := range attached {
+		a.send("io:" + string(p))
+	}
+	return len(p), nil
+}
@@ -0,0 +1,135 @@
+package fakeengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Process is an emulated process running in a container.
+type Process struct {
+	Args       []string
+	Env        []string
+	Interfaces []Interface
+	// Input sent by attached clients. Reads return io.EOF when a client has
+	// closed the input, or when the container is stopped.
+	Stdin  io.Reader
+	Stdout io.Writer
+	// Closed when the container is stopped.
+	Stop <-chan struct{}
+}
+
+// Interface is a network interface of a container.
+type Interface struct {
+	Name   string
+	Subnet string
+}
+
+// Command emulates an executable. It returns the exit code of the process,
+// and must return promptly when the Stop channel of the process is closed.
+type Command func(p *Process) int
+
+// DefaultCommands returns the executables that are emulated by default.
+func DefaultCommands() map[string]Command {
+	return map[string]Command{
+		"cat":     Cat,
+		"echo":    Echo,
+		"false":   func(p *Process) int { return 1 },
+		"ls":      Ls,
+		"netstat": Netstat,
+		"sleep":   Sleep,
+		"true":    func(p *Process) int { return 0 },
+	}
+}
+
+func (e *Engine) execute(p *Process) int {
+	if len(p.Args) == 0 {
+		return 0
+	}
+	command, exists := e.Commands[p.Args[0]]
+	if !exists {
+		command, exists = e.Commands[path.Base(p.Args[0])]
+	}
+	if !exists {
+		fmt.Fprintf(p.Stdout, "jail: execvp: %s: No such file or directory\n", p.Args[0])
+		return 127
+	}
+	return command(p)
+}
+
+// Echo writes its arguments.
+func Echo(p *Process) int {
+	fmt.Fprintln(p.Stdout, strings.Join(p.Args[1:], " "))
+	return 0
+}
+
+// Cat writes everything read from stdin, until stdin is closed or the container is stopped.
+func Cat(p *Process) int {
+	copied := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(p.Stdout, p.Stdin)
+		close(copied)
+	}()
+	select {
+	case <-copied:
+	case <-p.Stop:
+	}
+	return 0
+}
+
+// Ls lists the root directory of the 'base' image.
+func Ls(p *Process) int {
+	fmt.Fprint(p.Stdout, ".cshrc\n.profile\nCOPYRIGHT\nbin\nboot\ndev\netc\nlib\nlibexec\nmedia\nmnt\nnet\nproc\nrescue\nroot\nsbin\nsys\ntmp\nusr\nvar\n")
+	return 0
+}
+
+// Sleep waits for the given number of seconds, or until the container is stopped.
+func Sleep(p *Process) int {
+	seconds := 0.0
+	if len(p.Args) > 1 {
+		seconds, _ = strconv.ParseFloat(p.Args[1], 64)
+	}
+	select {
+	case <-time.After(time.Duration(seconds * float64(time.Second))):
+	case <-p.Stop:
+	}
+	return 0
+}
+
+// Netstat emulates 'netstat --libxo json -4 -i', listing the interfaces of the container.
+func Netstat(p *Process) int {
+	type interface_info struct {
+		Name            string `json:"name"`
+		Flags           string `json:"flags"`
+		Network         string `json:"network"`
+		Address         string `json:"address"`
+		ReceivedPackets int    `json:"received-packets"`
+		SentPackets     int    `json:"sent-packets"`
+	}
+
+	var status struct {
+		Statistics struct {
+			Interface []interface_info `json:"interface"`
+		} `json:"statistics"`
+	}
+	status.Statistics.Interface = []interface_info{}
+	for _, iface := range p.Interfaces {
+		info := interface_info{Name: iface.Name, Flags: "0x8049", Network: iface.Subnet}
+		if ip, _, err := net.ParseCIDR(iface.Subnet); err == nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				ip4[3]++
+				info.Address = ip4.String()
+			}
+		}
+		status.Statistics.Interface = append(status.Statistics.Interface, info)
+	}
+
+	output, _ := json.Marshal(status)
+	fmt.Fprintln(p.Stdout, string(output))
+	return 0
+}
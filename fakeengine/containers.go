@@ -0,0 +1,267 @@
+package fakeengine
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	Openapi "jcli/client"
+
+	"github.com/labstack/echo/v4"
+)
+
+var valid_container_name = regexp.MustCompile(`^/?[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+type container struct {
+	id         string
+	name       string
+	image      Openapi.Image
+	cmd        []string
+	env        []string
+	jail_param []string
+	volumes    []string
+	networks   []string
+	created    time.Time
+
+	running bool
+	// Stdin of the running process.
+	stdin *input
+	// Closed to stop the running process.
+	stop chan struct{}
+	// Closed when the running process has exited.
+	exited   chan struct{}
+	attached []*attachment
+	// Sent by attached clients in TTY mode.
+	terminal_size *terminal_size
+}
+
+func (c *container) summary() Openapi.ContainerSummary {
+	id, name, running := c.id, c.name, c.running
+	command := strings.Join(c.cmd, " ")
+	created := timestamp(c.created)
+	return Openapi.ContainerSummary{
+		Id:        &id,
+		Name:      &name,
+		Command:   &command,
+		Created:   &created,
+		Running:   &running,
+		ImageId:   c.image.Id,
+		ImageName: c.image.Name,
+		ImageTag:  c.image.Tag,
+	}
+}
+
+func (c *container) is_connected(network_id string) bool {
+	for _, id := range c.networks {
+		if id == network_id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *container) disconnect(network_id string) {
+	for i, id := range c.networks {
+		if id == network_id {
+			c.networks = append(c.networks[:i], c.networks[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *container) named_volumes() []string {
+	names := []string{}
+	for _, spec := range c.volumes {
+		if name, ok := is_named_volume(spec); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (e *Engine) ContainerCreate(ctx echo.Context, params Openapi.ContainerCreateParams) error {
+	var config Openapi.ContainerConfig
+	if err := ctx.Bind(&config); err != nil {
+		return error_response(ctx, http.StatusInternalServerError, "invalid container configuration: "+err.Error())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c := &container{id: new_id(), created: time.Now()}
+	c.name = c.id
+	if params.Name != nil {
+		if !valid_container_name.MatchString(*params.Name) {
+			return error_response(ctx, http.StatusInternalServerError, "invalid container name '"+*params.Name+"'")
+		}
+		c.name = strings.TrimPrefix(*params.Name, "/")
+	}
+	for _, existing := range e.containers {
+		if existing.name == c.name {
+			return error_response(ctx, http.StatusInternalServerError, "container name '"+c.name+"' is already in use")
+		}
+	}
+
+	if config.Image == nil {
+		return error_response(ctx, http.StatusInternalServerError, "no image given")
+	}
+	image := e.find_image(*config.Image)
+	if image == nil {
+		return error_response(ctx, http.StatusInternalServerError, "no such image '"+*config.Image+"'")
+	}
+	c.image = *image
+	c.cmd = *image.Command
+	if config.Cmd != nil && len(*config.Cmd) > 0 {
+		c.cmd = *config.Cmd
+	}
+
+	networks := []string{"default"}
+	if config.Networks != nil && len(*config.Networks) > 0 {
+		networks = *config.Networks
+	}
+	for _, name_or_id := range networks {
+		n := e.find_network(name_or_id)
+		if n == nil {
+			return error_response(ctx, http.StatusInternalServerError, "no such network '"+name_or_id+"'")
+		}
+		c.networks = append(c.networks, n.id)
+	}
+
+	if config.Env != nil {
+		c.env = *config.Env
+	}
+	if config.JailParam != nil {
+		c.jail_param = *config.JailParam
+	}
+	if config.Volumes != nil {
+		c.volumes = *config.Volumes
+	}
+	for _, name := range c.named_volumes() {
+		e.create_volume(name)
+	}
+
+	e.containers = append(e.containers, c)
+	return id_response(ctx, http.StatusCreated, c.id)
+}
+
+// ContainerList lists the most recently created containers first.
+func (e *Engine) ContainerList(ctx echo.Context, params Openapi.ContainerListParams) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	all := params.All != nil && *params.All
+	containers := []Openapi.ContainerSummary{}
+	for i := len(e.containers) - 1; i >= 0; i-- {
+		if all || e.containers[i].running {
+			containers = append(containers, e.containers[i].summary())
+		}
+	}
+	return ctx.JSON(http.StatusOK, containers)
+}
+
+func (e *Engine) ContainerDelete(ctx echo.Context, container_id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c := e.find_container(container_id)
+	if c == nil {
+		return error_response(ctx, http.StatusNotFound, "no such container")
+	}
+	if c.running {
+		return error_response(ctx, http.StatusInternalServerError, "you cannot remove a running container")
+	}
+	for i := range e.containers {
+		if e.containers[i] == c {
+			e.containers = append(e.containers[:i], e.containers[i+1:]...)
+			break
+		}
+	}
+	return id_response(ctx, http.StatusOK, c.id)
+}
+
+func (e *Engine) ContainerStart(ctx echo.Context, container_id string) error {
+	e.attach_lock.Lock()
+	defer e.attach_lock.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c := e.find_container(container_id)
+	if c == nil {
+		return error_response(ctx, http.StatusNotFound, "no such container")
+	}
+	if c.running {
+		// A 304 response cannot have a body.
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	c.running = true
+	c.stdin = new_input()
+	c.stop = make(chan struct{})
+	c.exited = make(chan struct{})
+	process := &Process{
+		Args:       c.cmd,
+		Env:        c.env,
+		Interfaces: e.interfaces(c),
+		Stdin:      c.stdin,
+		Stdout:     &output{engine: e, container: c},
+		Stop:       c.stop,
+	}
+	go e.run(c, process)
+	return id_response(ctx, http.StatusOK, c.id)
+}
+
+func (e *Engine) ContainerStop(ctx echo.Context, container_id string) error {
+	e.mu.Lock()
+	c := e.find_container(container_id)
+	if c == nil {
+		e.mu.Unlock()
+		return error_response(ctx, http.StatusNotFound, "no such container")
+	}
+	if !c.running {
+		e.mu.Unlock()
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	stop, exited := c.stop, c.exited
+	c.stop = nil
+	e.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+	}
+	<-exited
+	return id_response(ctx, http.StatusOK, c.id)
+}
+
+// run executes the process of the container, and closes every attached
+// websocket when it exits.
+func (e *Engine) run(c *container, process *Process) {
+	e.execute(process)
+
+	e.mu.Lock()
+	c.running = false
+	c.stdin.Close()
+	c.stdin = nil
+	attached := c.attached
+	c.attached = nil
+	close(c.exited)
+	e.mu.Unlock()
+
+	for _, a := range attached {
+		a.close_with_exit(c.id)
+	}
+}
+
+// Must be called with e.mu held.
+func (e *Engine) find_container(name_or_id string) *container {
+	idx := lookup(
+		name_or_id,
+		func(i int) string { return e.containers[i].id },
+		func(i int) string { return e.containers[i].name },
+		len(e.containers),
+	)
+	if idx == -1 {
+		return nil
+	}
+	return e.containers[idx]
+}
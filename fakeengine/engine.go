@@ -0,0 +1,159 @@
+// Package fakeengine provides an in-memory jocker engine for testing jcli
+// without a FreeBSD host. It implements every operation of Openapi.ServerInterface
+// together with the websocket protocol used for attaching to containers.
+// Processes in containers are emulated by the Commands of the engine.
+package fakeengine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	Openapi "jcli/client"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Engine is an in-memory jocker engine.
+type Engine struct {
+	// Emulated executables, looked up by path and then by basename.
+	Commands map[string]Command
+
+	mu         sync.Mutex
+	containers []*container
+	images     []*Openapi.Image
+	networks   []*network
+	volumes    []*volume
+
+	// Held while a websocket is being attached, so that a container is never
+	// started before a client that has completed the handshake is registered.
+	attach_lock sync.RWMutex
+}
+
+// New returns an engine with the 'base' image and the 'default' and 'host'
+// networks, like a freshly installed engine.
+func New() *Engine {
+	engine := &Engine{Commands: DefaultCommands()}
+	engine.AddImage("base", "latest", []string{"/bin/sh", "/etc/rc"})
+	engine.networks = []*network{
+		{id: new_id(), name: "default", driver: "loopback", if_name: "jocker0", subnet: "172.17.0.0/16", default_gw_if: true},
+		{id: new_id(), name: "host", driver: "host"},
+	}
+	return engine
+}
+
+// NewServer starts a new engine listening on a random local port.
+func NewServer() (*Engine, *httptest.Server) {
+	engine := New()
+	return engine, httptest.NewServer(engine.Handler())
+}
+
+// Handler returns the http handler serving the engine API.
+func (e *Engine) Handler() http.Handler {
+	router := echo.New()
+	router.HideBanner = true
+	router.Use(middleware.RequestID())
+	Openapi.RegisterHandlers(router, e)
+	router.GET("/containers/:container_id/attach", e.ContainerAttach)
+	return router
+}
+
+// AddImage adds an image that containers can be created from.
+func (e *Engine) AddImage(name, tag string, command []string) Openapi.Image {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	id := new_id()
+	layer_id := new_id()
+	created := timestamp(time.Now())
+	user := "root"
+	image := &Openapi.Image{
+		Id:      &id,
+		Name:    &name,
+		Tag:     &tag,
+		Command: &command,
+		Created: &created,
+		LayerId: &layer_id,
+		EnvVars: &[]string{},
+		User:    &user,
+	}
+	e.images = append(e.images, image)
+	return *image
+}
+
+// IsRunning reports whether the container exists and is running.
+func (e *Engine) IsRunning(name_or_id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c := e.find_container(name_or_id)
+	return c != nil && c.running
+}
+
+// TerminalSize returns the last terminal size sent by a client attached to the
+// container, and false if none has been sent.
+func (e *Engine) TerminalSize(name_or_id string) (width int, height int, sent bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c := e.find_container(name_or_id)
+	if c == nil || c.terminal_size == nil {
+		return 0, 0, false
+	}
+	return c.terminal_size.Width, c.terminal_size.Height, true
+}
+
+// The engine accepts an exact id, an exact name or a unique prefix of an id.
+func lookup(name_or_id string, id func(int) string, name func(int) string, count int) int {
+	prefix_match := -1
+	for i := 0; i < count; i++ {
+		if id(i) == name_or_id || name(i) == name_or_id {
+			return i
+		}
+		if name_or_id != "" && strings.HasPrefix(id(i), name_or_id) {
+			if prefix_match != -1 {
+				return -1
+			}
+			prefix_match = i
+		}
+	}
+	return prefix_match
+}
+
+func (e *Engine) find_image(name_or_id string) *Openapi.Image {
+	name, tag := name_or_id, "latest"
+	if idx := strings.LastIndex(name_or_id, ":"); idx != -1 {
+		name, tag = name_or_id[:idx], name_or_id[idx+1:]
+	}
+	for _, image := range e.images {
+		if *image.Name == name && *image.Tag == tag {
+			return image
+		}
+	}
+	idx := lookup(name_or_id, func(i int) string { return *e.images[i].Id }, func(int) string { return "" }, len(e.images))
+	if idx == -1 {
+		return nil
+	}
+	return e.images[idx]
+}
+
+func error_response(ctx echo.Context, status int, message string) error {
+	return ctx.JSON(status, Openapi.ErrorResponse{Message: message})
+}
+
+func id_response(ctx echo.Context, status int, id string) error {
+	return ctx.JSON(status, Openapi.IdResponse{Id: id})
+}
+
+func new_id() string {
+	id := make([]byte, 6)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,147 @@
+package fakeengine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	Openapi "jcli/client"
+
+	"github.com/gorilla/websocket"
+	"gotest.tools/v3/assert"
+)
+
+func TestContainerLifecycle(t *testing.T) {
+	engine, server := NewServer()
+	defer server.Close()
+	client, err := Openapi.NewClientWithResponses(server.URL)
+	assert.NilError(t, err)
+	ctx := context.Background()
+
+	image := "base"
+	name := "lifecycle"
+	created, err := client.ContainerCreateWithResponse(ctx, &Openapi.ContainerCreateParams{Name: &name}, Openapi.ContainerCreateJSONRequestBody{Image: &image, Cmd: &[]string{"/bin/sleep", "10"}})
+	assert.NilError(t, err)
+	assert.Equal(t, created.StatusCode(), http.StatusCreated)
+	id := created.JSON201.Id
+
+	t.Run("creating a container with a taken name fails", func(t *testing.T) {
+		response, err := client.ContainerCreateWithResponse(ctx, &Openapi.ContainerCreateParams{Name: &name}, Openapi.ContainerCreateJSONRequestBody{Image: &image})
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode(), http.StatusInternalServerError)
+		assert.Assert(t, strings.Contains(response.JSON500.Message, "already in use"))
+	})
+
+	t.Run("containers can be started by an id prefix", func(t *testing.T) {
+		response, err := client.ContainerStartWithResponse(ctx, id[:4])
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode(), http.StatusOK)
+		assert.Assert(t, engine.IsRunning(name))
+	})
+
+	t.Run("starting a running container is not modified", func(t *testing.T) {
+		response, err := client.ContainerStartWithResponse(ctx, name)
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode(), http.StatusNotModified)
+	})
+
+	t.Run("running containers cannot be removed", func(t *testing.T) {
+		response, err := client.ContainerDeleteWithResponse(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode(), http.StatusInternalServerError)
+	})
+
+	t.Run("stopping a container waits for it to exit", func(t *testing.T) {
+		response, err := client.ContainerStopWithResponse(ctx, name)
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode(), http.StatusOK)
+		assert.Assert(t, !engine.IsRunning(name))
+	})
+
+	t.Run("stopping a stopped container is not modified", func(t *testing.T) {
+		response, err := client.ContainerStopWithResponse(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode(), http.StatusNotModified)
+	})
+
+	t.Run("remove the container", func(t *testing.T) {
+		response, err := client.ContainerDeleteWithResponse(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode(), http.StatusOK)
+		assert.Equal(t, response.JSON200.Id, id)
+	})
+
+	t.Run("unknown containers are not found", func(t *testing.T) {
+		response, err := client.ContainerStartWithResponse(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, response.StatusCode(), http.StatusNotFound)
+		assert.Equal(t, response.JSON404.Message, "no such container")
+	})
+}
+
+func TestAttachProtocol(t *testing.T) {
+	_, server := NewServer()
+	defer server.Close()
+	client, err := Openapi.NewClientWithResponses(server.URL)
+	assert.NilError(t, err)
+	ctx := context.Background()
+
+	image := "base"
+	created, err := client.ContainerCreateWithResponse(ctx, &Openapi.ContainerCreateParams{}, Openapi.ContainerCreateJSONRequestBody{Image: &image, Cmd: &[]string{"/bin/echo", "hello"}})
+	assert.NilError(t, err)
+	id := created.JSON201.Id
+
+	ws_url := "ws" + strings.TrimPrefix(server.URL, "http") + "/containers/" + id + "/attach"
+	ws, _, err := websocket.DefaultDialer.Dial(ws_url, nil)
+	assert.NilError(t, err)
+	defer ws.Close()
+	_, message, err := ws.ReadMessage()
+	assert.NilError(t, err)
+	assert.Equal(t, string(message), "ok:")
+
+	_, err = client.ContainerStartWithResponse(ctx, id)
+	assert.NilError(t, err)
+	_, message, err = ws.ReadMessage()
+	assert.NilError(t, err)
+	assert.Equal(t, string(message), "io:hello\n")
+
+	_, _, err = ws.ReadMessage()
+	close_error, is_close_error := err.(*websocket.CloseError)
+	assert.Assert(t, is_close_error)
+	assert.Equal(t, close_error.Code, websocket.CloseNormalClosure)
+	assert.Equal(t, close_error.Text, "exit:container "+id+" stopped")
+}
+
+func TestAttachInput(t *testing.T) {
+	_, server := NewServer()
+	defer server.Close()
+	client, err := Openapi.NewClientWithResponses(server.URL)
+	assert.NilError(t, err)
+	ctx := context.Background()
+
+	image := "base"
+	created, err := client.ContainerCreateWithResponse(ctx, &Openapi.ContainerCreateParams{}, Openapi.ContainerCreateJSONRequestBody{Image: &image, Cmd: &[]string{"/bin/cat"}})
+	assert.NilError(t, err)
+	id := created.JSON201.Id
+
+	ws_url := "ws" + strings.TrimPrefix(server.URL, "http") + "/containers/" + id + "/attach"
+	ws, _, err := websocket.DefaultDialer.Dial(ws_url, nil)
+	assert.NilError(t, err)
+	defer ws.Close()
+	_, _, err = ws.ReadMessage()
+	assert.NilError(t, err)
+
+	_, err = client.ContainerStartWithResponse(ctx, id)
+	assert.NilError(t, err)
+	assert.NilError(t, ws.WriteMessage(websocket.BinaryMessage, []byte("io:hello\n")))
+	_, message, err := ws.ReadMessage()
+	assert.NilError(t, err)
+	assert.Equal(t, string(message), "io:hello\n")
+
+	assert.NilError(t, ws.WriteMessage(websocket.TextMessage, []byte("eof:")))
+	_, _, err = ws.ReadMessage()
+	close_error, is_close_error := err.(*websocket.CloseError)
+	assert.Assert(t, is_close_error)
+	assert.Equal(t, close_error.Text, "exit:container "+id+" stopped")
+}
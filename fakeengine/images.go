@@ -0,0 +1,37 @@
+package fakeengine
+
+import (
+	"net/http"
+
+	Openapi "jcli/client"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (e *Engine) ImageList(ctx echo.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	images := make([]Openapi.Image, len(e.images))
+	for i, image := range e.images {
+		images[i] = *image
+	}
+	return ctx.JSON(http.StatusOK, images)
+}
+
+func (e *Engine) ImageRemove(ctx echo.Context, image_id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	image := e.find_image(image_id)
+	if image == nil {
+		return error_response(ctx, http.StatusNotFound, "no such image")
+	}
+	for i := range e.images {
+		if e.images[i] == image {
+			e.images = append(e.images[:i], e.images[i+1:]...)
+			break
+		}
+	}
+	return id_response(ctx, http.StatusOK, *image.Id)
+}
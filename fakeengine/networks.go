@@ -0,0 +1,170 @@
+package fakeengine
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	Openapi "jcli/client"
+
+	"github.com/labstack/echo/v4"
+)
+
+type network struct {
+	id            string
+	name          string
+	driver        string
+	if_name       string
+	subnet        string
+	default_gw_if bool
+}
+
+func (n *network) summary() Openapi.NetworkSummary {
+	id, name, driver, if_name, subnet, default_gw_if := n.id, n.name, n.driver, n.if_name, n.subnet, n.default_gw_if
+	return Openapi.NetworkSummary{Id: &id, Name: &name, Driver: &driver, IfName: &if_name, Subnet: &subnet, DefaultGwIf: &default_gw_if}
+}
+
+func (e *Engine) NetworkCreate(ctx echo.Context) error {
+	var config Openapi.NetworkConfig
+	if err := ctx.Bind(&config); err != nil {
+		return error_response(ctx, http.StatusInternalServerError, "invalid network configuration: "+err.Error())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	driver := "loopback"
+	if config.Driver != nil && *config.Driver != "" {
+		driver = *config.Driver
+	}
+	if driver != "loopback" {
+		return error_response(ctx, http.StatusConflict, "unknown driver '"+driver+"', only 'loopback' networks can be created")
+	}
+	if config.Name == "" {
+		return error_response(ctx, http.StatusConflict, "a network name must be given")
+	}
+	if e.find_network(config.Name) != nil {
+		return error_response(ctx, http.StatusConflict, "network name is already taken")
+	}
+	if config.Subnet == nil {
+		return error_response(ctx, http.StatusConflict, "a subnet must be given")
+	}
+	if _, _, err := net.ParseCIDR(*config.Subnet); err != nil {
+		return error_response(ctx, http.StatusConflict, "invalid subnet: "+err.Error())
+	}
+
+	if_name := fmt.Sprintf("jocker%d", len(e.networks))
+	if config.Ifname != nil && *config.Ifname != "" {
+		if_name = *config.Ifname
+	}
+
+	n := &network{id: new_id(), name: config.Name, driver: driver, if_name: if_name, subnet: *config.Subnet}
+	e.networks = append(e.networks, n)
+	return id_response(ctx, http.StatusCreated, n.id)
+}
+
+func (e *Engine) NetworkList(ctx echo.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	networks := make([]Openapi.NetworkSummary, len(e.networks))
+	for i, n := range e.networks {
+		networks[i] = n.summary()
+	}
+	return ctx.JSON(http.StatusOK, networks)
+}
+
+func (e *Engine) NetworkRemove(ctx echo.Context, network_id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n := e.find_network(network_id)
+	if n == nil {
+		return error_response(ctx, http.StatusNotFound, "no such network")
+	}
+	if n.driver == "host" {
+		return error_response(ctx, http.StatusInternalServerError, "the host network cannot be removed")
+	}
+
+	for _, c := range e.containers {
+		c.disconnect(n.id)
+	}
+	for i := range e.networks {
+		if e.networks[i] == n {
+			e.networks = append(e.networks[:i], e.networks[i+1:]...)
+			break
+		}
+	}
+	return id_response(ctx, http.StatusOK, n.id)
+}
+
+func (e *Engine) NetworkConnect(ctx echo.Context, network_id string, container_id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n := e.find_network(network_id)
+	if n == nil {
+		return error_response(ctx, http.StatusNotFound, "no such network")
+	}
+	c := e.find_container(container_id)
+	if c == nil {
+		return error_response(ctx, http.StatusNotFound, "no such container")
+	}
+	if c.is_connected(n.id) {
+		return error_response(ctx, http.StatusConflict, "container already connected to the network")
+	}
+	if n.driver == "host" && len(c.networks) > 0 || e.is_on_host_network(c) {
+		return error_response(ctx, http.StatusConflict, "containers using the host network cannot be connected to other networks")
+	}
+	c.networks = append(c.networks, n.id)
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (e *Engine) NetworkDisconnect(ctx echo.Context, network_id string, container_id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n := e.find_network(network_id)
+	c := e.find_container(container_id)
+	if n == nil || c == nil || !c.is_connected(n.id) {
+		return error_response(ctx, http.StatusNotFound, "no such network and/or container")
+	}
+	c.disconnect(n.id)
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// Must be called with e.mu held.
+func (e *Engine) find_network(name_or_id string) *network {
+	idx := lookup(
+		name_or_id,
+		func(i int) string { return e.networks[i].id },
+		func(i int) string { return e.networks[i].name },
+		len(e.networks),
+	)
+	if idx == -1 {
+		return nil
+	}
+	return e.networks[idx]
+}
+
+// Must be called with e.mu held.
+func (e *Engine) is_on_host_network(c *container) bool {
+	for _, network_id := range c.networks {
+		if n := e.find_network(network_id); n != nil && n.driver == "host" {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaces returns the interfaces that the container has been given by its networks.
+// Must be called with e.mu held.
+func (e *Engine) interfaces(c *container) []Interface {
+	interfaces := []Interface{}
+	for _, network_id := range c.networks {
+		if n := e.find_network(network_id); n != nil && n.driver == "loopback" {
+			interfaces = append(interfaces, Interface{Name: n.if_name, Subnet: n.subnet})
+		}
+	}
+	return interfaces
+}
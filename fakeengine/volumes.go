@@ -0,0 +1,92 @@
+package fakeengine
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	Openapi "jcli/client"
+
+	"github.com/labstack/echo/v4"
+)
+
+type volume struct {
+	name    string
+	created time.Time
+}
+
+func (v *volume) summary() Openapi.VolumeSummary {
+	created := timestamp(v.created)
+	dataset := "zroot/jocker/volumes/" + v.name
+	mountpoint := "/" + dataset
+	name := v.name
+	return Openapi.VolumeSummary{Name: &name, Created: &created, Dataset: &dataset, Mountpoint: &mountpoint}
+}
+
+func (e *Engine) VolumeCreate(ctx echo.Context) error {
+	var config Openapi.VolumeConfig
+	if err := ctx.Bind(&config); err != nil {
+		return error_response(ctx, http.StatusInternalServerError, "invalid volume configuration: "+err.Error())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if config.Name == "" {
+		return error_response(ctx, http.StatusInternalServerError, "a volume name must be given")
+	}
+	e.create_volume(config.Name)
+	// A 204 response cannot have a body, even though the API specifies an IdResponse.
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (e *Engine) VolumeList(ctx echo.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	volumes := make([]Openapi.VolumeSummary, len(e.volumes))
+	for i, v := range e.volumes {
+		volumes[i] = v.summary()
+	}
+	return ctx.JSON(http.StatusOK, volumes)
+}
+
+func (e *Engine) VolumeRemove(ctx echo.Context, volume_name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, v := range e.volumes {
+		if v.name != volume_name {
+			continue
+		}
+		for _, c := range e.containers {
+			for _, name := range c.named_volumes() {
+				if name == volume_name {
+					return error_response(ctx, http.StatusInternalServerError, "volume is in use by container "+c.id)
+				}
+			}
+		}
+		e.volumes = append(e.volumes[:i], e.volumes[i+1:]...)
+		return id_response(ctx, http.StatusOK, volume_name)
+	}
+	return error_response(ctx, http.StatusNotFound, "no such volume")
+}
+
+// create_volume creates the volume unless it already exists. Must be called with e.mu held.
+func (e *Engine) create_volume(name string) {
+	for _, v := range e.volumes {
+		if v.name == name {
+			return
+		}
+	}
+	e.volumes = append(e.volumes, &volume{name: name, created: time.Now()})
+}
+
+// Volumes are given as 'name:/path/in/container' or '/host/path:/path/in/container'.
+// Only the former refers to a named volume.
+func is_named_volume(spec string) (string, bool) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || strings.HasPrefix(parts[0], "/") {
+		return "", false
+	}
+	return parts[0], true
+}
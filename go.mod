@@ -5,10 +5,14 @@ go 1.16
 require (
 	github.com/deepmap/oapi-codegen v1.8.2
 	github.com/getkin/kin-openapi v0.61.0
+	github.com/ghodss/yaml v1.0.0
 	github.com/gorilla/websocket v1.4.2
 	github.com/labstack/echo/v4 v4.2.1
+	github.com/mattn/go-runewidth v0.0.9
 	github.com/spf13/cobra v1.2.1
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.8.1
+	golang.org/x/term v0.0.0-20210503060354-a79de5458b56
 	gotest.tools v2.2.0+incompatible
 	gotest.tools/v3 v3.0.3
 )
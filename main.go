@@ -2,8 +2,11 @@ package main
 
 import (
 	"jcli/cli"
+	"os"
 )
 
 func main() {
-	cli.Execute()
+	if err := cli.Execute(); err != nil {
+		os.Exit(cli.ExitCode(err))
+	}
 }